@@ -0,0 +1,472 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+const (
+	// UnsetServicesAnnotation lists the services of a v1alpha2 Stack which are not set, but are mandatory in v1alpha1,
+	// i.e. tinkServer and tinkController. It lets the v1alpha1 representation convert back to the original v1alpha2
+	// Stack.
+	UnsetServicesAnnotation = "conversion.tinkerbell.org/unset-services"
+	// ComponentDeploymentsAnnotation holds the deployment settings of the v1alpha2 components which v1alpha1 can't
+	// represent as JSON keyed by the component, i.e. all the settings of the services but the resources and all the
+	// settings of the nginx proxy and the Hook download.
+	ComponentDeploymentsAnnotation = "conversion.tinkerbell.org/component-deployments"
+)
+
+const (
+	smeeService           = "smee"
+	hegelService          = "hegel"
+	rufioService          = "rufio"
+	tinkServerService     = "tinkServer"
+	tinkControllerService = "tinkController"
+	nginxComponent        = "nginx"
+	hookDownloadComponent = "hookDownload"
+)
+
+var _ conversion.Convertible = &Stack{}
+
+// ConvertTo converts the Stack to the v1alpha2 hub version.
+func (src *Stack) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha2.Stack)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha2 Stack but got %T", dstRaw)
+	}
+
+	in := src.DeepCopy()
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = specToV1alpha2(in.Spec)
+	dst.Status = statusToV1alpha2(in.Status)
+
+	if data, ok := dst.Annotations[ComponentDeploymentsAnnotation]; ok {
+		settings := map[string]v1alpha2.ComponentDeployment{}
+		if err := json.Unmarshal([]byte(data), &settings); err != nil {
+			return fmt.Errorf("failed to decode the %s annotation: %w", ComponentDeploymentsAnnotation, err)
+		}
+
+		// The resources of the services are represented in v1alpha1, they are kept as they are.
+		deployments := componentDeployments(&dst.Spec.Services)
+		for component, s := range settings {
+			s := s
+			if deployment, ok := deployments[component]; ok {
+				s.Resources = deployment.Resources
+				*deployment = s
+				continue
+			}
+
+			switch component {
+			case nginxComponent:
+				dst.Spec.Nginx = &v1alpha2.Nginx{ComponentDeployment: s}
+			case hookDownloadComponent:
+				if dst.Spec.HookArtifacts != nil {
+					dst.Spec.HookArtifacts.Download = &s
+				}
+			}
+		}
+	}
+
+	if unset, ok := dst.Annotations[UnsetServicesAnnotation]; ok {
+		// The services are only unset again if they weren't changed through v1alpha1 in the meantime.
+		for _, service := range strings.Split(unset, ",") {
+			switch service {
+			case tinkServerService:
+				if reflect.DeepEqual(in.Spec.Services.TinkServer, TinkServer{}) {
+					dst.Spec.Services.TinkServer = nil
+				}
+			case tinkControllerService:
+				if reflect.DeepEqual(in.Spec.Services.TinkController, TinkController{}) {
+					dst.Spec.Services.TinkController = nil
+				}
+			}
+		}
+	}
+
+	removeAnnotations(&dst.ObjectMeta, ComponentDeploymentsAnnotation, UnsetServicesAnnotation)
+
+	return nil
+}
+
+// ConvertFrom converts the v1alpha2 hub version to the Stack.
+func (dst *Stack) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha2.Stack)
+	if !ok {
+		return fmt.Errorf("expected a v1alpha2 Stack but got %T", srcRaw)
+	}
+
+	in := src.DeepCopy()
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = specFromV1alpha2(in.Spec)
+	dst.Status = statusFromV1alpha2(in.Status)
+
+	settings := map[string]v1alpha2.ComponentDeployment{}
+	for service, deployment := range componentDeployments(&in.Spec.Services) {
+		s := *deployment
+		s.Resources = nil
+		if !reflect.DeepEqual(s, v1alpha2.ComponentDeployment{}) {
+			settings[service] = s
+		}
+	}
+
+	// The settings of the nginx proxy and the Hook download are kept even if they are empty, so that they aren't unset
+	// by the conversion.
+	if in.Spec.Nginx != nil {
+		settings[nginxComponent] = in.Spec.Nginx.ComponentDeployment
+	}
+
+	if in.Spec.HookArtifacts != nil && in.Spec.HookArtifacts.Download != nil {
+		settings[hookDownloadComponent] = *in.Spec.HookArtifacts.Download
+	}
+
+	if len(settings) > 0 {
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return fmt.Errorf("failed to encode the %s annotation: %w", ComponentDeploymentsAnnotation, err)
+		}
+		setAnnotation(&dst.ObjectMeta, ComponentDeploymentsAnnotation, string(data))
+	}
+
+	var unset []string
+	if in.Spec.Services.TinkServer == nil {
+		unset = append(unset, tinkServerService)
+	}
+
+	if in.Spec.Services.TinkController == nil {
+		unset = append(unset, tinkControllerService)
+	}
+
+	if len(unset) > 0 {
+		setAnnotation(&dst.ObjectMeta, UnsetServicesAnnotation, strings.Join(unset, ","))
+	}
+
+	return nil
+}
+
+// componentDeployments returns the deployment settings of the services which are set, keyed by the service.
+func componentDeployments(services *v1alpha2.Services) map[string]*v1alpha2.ComponentDeployment {
+	deployments := map[string]*v1alpha2.ComponentDeployment{}
+
+	if services.Smee != nil {
+		deployments[smeeService] = &services.Smee.ComponentDeployment
+	}
+
+	if services.Hegel != nil {
+		deployments[hegelService] = &services.Hegel.ComponentDeployment
+	}
+
+	if services.Rufio != nil {
+		deployments[rufioService] = &services.Rufio.ComponentDeployment
+	}
+
+	if services.TinkServer != nil {
+		deployments[tinkServerService] = &services.TinkServer.ComponentDeployment
+	}
+
+	if services.TinkController != nil {
+		deployments[tinkControllerService] = &services.TinkController.ComponentDeployment
+	}
+
+	return deployments
+}
+
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[key] = value
+}
+
+func removeAnnotations(meta *metav1.ObjectMeta, keys ...string) {
+	if meta.Annotations == nil {
+		return
+	}
+
+	for _, key := range keys {
+		delete(meta.Annotations, key)
+	}
+
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+}
+
+// The conversion functions below take ownership of their input, callers pass a deep copy. Structs whose fields are of
+// the same types in both versions are converted directly.
+
+func specToV1alpha2(in StackSpec) v1alpha2.StackSpec {
+	out := v1alpha2.StackSpec{
+		Version:           in.Version,
+		Services:          servicesToV1alpha2(in.Services),
+		DNSResolverIP:     in.DNSResolverIP,
+		Registry:          in.Registry,
+		ImagePullSecrets:  in.ImagePullSecrets,
+		CRDDeletionPolicy: v1alpha2.CRDDeletionPolicy(in.CRDDeletionPolicy),
+		Airgapped:         in.Airgapped,
+		Upgrade:           (*v1alpha2.UpgradeStrategy)(in.Upgrade),
+	}
+
+	if a := in.PublicAddress; a != nil {
+		out.PublicAddress = &v1alpha2.PublicAddress{
+			IP:                  a.IP,
+			Discovery:           v1alpha2.PublicAddressDiscovery(a.Discovery),
+			LoadBalancerService: a.LoadBalancerService,
+		}
+	}
+
+	if a := in.HookArtifacts; a != nil {
+		out.HookArtifacts = &v1alpha2.HookArtifacts{
+			Version:       a.Version,
+			Architectures: a.Architectures,
+			URL:           a.URL,
+			Checksums:     a.Checksums,
+		}
+
+		if s := a.Source; s != nil {
+			out.HookArtifacts.Source = &v1alpha2.HookArtifactsSource{
+				Image:                 (*v1alpha2.HookImageSource)(s.Image),
+				PersistentVolumeClaim: (*v1alpha2.HookVolumeSource)(s.PersistentVolumeClaim),
+			}
+		}
+
+		if s := a.Storage; s != nil {
+			out.HookArtifacts.Storage = &v1alpha2.HookStorage{
+				Type:                  v1alpha2.HookStorageType(s.Type),
+				PersistentVolumeClaim: (*v1alpha2.HookPersistentVolumeClaim)(s.PersistentVolumeClaim),
+				HostPath:              (*v1alpha2.HookHostPath)(s.HostPath),
+			}
+		}
+	}
+
+	return out
+}
+
+func specFromV1alpha2(in v1alpha2.StackSpec) StackSpec {
+	out := StackSpec{
+		Version:           in.Version,
+		Services:          servicesFromV1alpha2(in.Services),
+		DNSResolverIP:     in.DNSResolverIP,
+		Registry:          in.Registry,
+		ImagePullSecrets:  in.ImagePullSecrets,
+		CRDDeletionPolicy: CRDDeletionPolicy(in.CRDDeletionPolicy),
+		Airgapped:         in.Airgapped,
+		Upgrade:           (*UpgradeStrategy)(in.Upgrade),
+	}
+
+	if a := in.PublicAddress; a != nil {
+		out.PublicAddress = &PublicAddress{
+			IP:                  a.IP,
+			Discovery:           PublicAddressDiscovery(a.Discovery),
+			LoadBalancerService: a.LoadBalancerService,
+		}
+	}
+
+	if a := in.HookArtifacts; a != nil {
+		out.HookArtifacts = &HookArtifacts{
+			Version:       a.Version,
+			Architectures: a.Architectures,
+			URL:           a.URL,
+			Checksums:     a.Checksums,
+		}
+
+		if s := a.Source; s != nil {
+			out.HookArtifacts.Source = &HookArtifactsSource{
+				Image:                 (*HookImageSource)(s.Image),
+				PersistentVolumeClaim: (*HookVolumeSource)(s.PersistentVolumeClaim),
+			}
+		}
+
+		if s := a.Storage; s != nil {
+			out.HookArtifacts.Storage = &HookStorage{
+				Type:                  HookStorageType(s.Type),
+				PersistentVolumeClaim: (*HookPersistentVolumeClaim)(s.PersistentVolumeClaim),
+				HostPath:              (*HookHostPath)(s.HostPath),
+			}
+		}
+	}
+
+	return out
+}
+
+func servicesToV1alpha2(in Services) v1alpha2.Services {
+	out := v1alpha2.Services{
+		TinkServer: &v1alpha2.TinkServer{
+			Image:     v1alpha2.Image(in.TinkServer.Image),
+			EnableTLS: in.TinkServer.EnableTLS,
+			Port:      in.TinkServer.Port,
+			ComponentDeployment: v1alpha2.ComponentDeployment{
+				Resources: in.TinkServer.Resources,
+			},
+		},
+		TinkController: &v1alpha2.TinkController{
+			Image: v1alpha2.Image(in.TinkController.Image),
+			ComponentDeployment: v1alpha2.ComponentDeployment{
+				Resources: in.TinkController.Resources,
+			},
+		},
+	}
+
+	if cm := in.TinkServer.CertManager; cm != nil {
+		out.TinkServer.CertManager = &v1alpha2.CertManager{IssuerRef: v1alpha2.CertManagerIssuerRef(cm.IssuerRef)}
+	}
+
+	if s := in.Smee; s != nil {
+		out.Smee = &v1alpha2.Smee{
+			Image: v1alpha2.Image(s.Image),
+			BackendConfigs: v1alpha2.BackendConfigs{
+				BackendKubeMode: (*v1alpha2.BackendKubeMode)(s.BackendConfigs.BackendKubeMode),
+				BackendFileMode: (*v1alpha2.BackendFileMode)(s.BackendConfigs.BackendFileMode),
+			},
+			SyslogConfigs: (*v1alpha2.SyslogConfigs)(s.SyslogConfigs),
+			TFTPConfigs:   (*v1alpha2.TFTPConfigs)(s.TFTPConfigs),
+			IPXEConfigs:   (*v1alpha2.IPXEConfigs)(s.IPXEConfigs),
+			DHCPConfigs:   (*v1alpha2.DHCPConfigs)(s.DHCPConfigs),
+			LogLevel:      s.LogLevel,
+			ComponentDeployment: v1alpha2.ComponentDeployment{
+				Resources: s.Resources,
+			},
+		}
+	}
+
+	if h := in.Hegel; h != nil {
+		out.Hegel = &v1alpha2.Hegel{
+			Image:          v1alpha2.Image(h.Image),
+			TrustedProxies: h.TrustedProxies,
+			Port:           h.Port,
+			ComponentDeployment: v1alpha2.ComponentDeployment{
+				Resources: h.Resources,
+			},
+		}
+	}
+
+	if r := in.Rufio; r != nil {
+		out.Rufio = &v1alpha2.Rufio{
+			Image: v1alpha2.Image(r.Image),
+			ComponentDeployment: v1alpha2.ComponentDeployment{
+				Resources: r.Resources,
+			},
+		}
+	}
+
+	return out
+}
+
+func servicesFromV1alpha2(in v1alpha2.Services) Services {
+	out := Services{}
+
+	if t := in.TinkServer; t != nil {
+		out.TinkServer = TinkServer{
+			Image:     Image(t.Image),
+			EnableTLS: t.EnableTLS,
+			Port:      t.Port,
+			Resources: t.Resources,
+		}
+
+		if cm := t.CertManager; cm != nil {
+			out.TinkServer.CertManager = &CertManager{IssuerRef: CertManagerIssuerRef(cm.IssuerRef)}
+		}
+	}
+
+	if t := in.TinkController; t != nil {
+		out.TinkController = TinkController{
+			Image:     Image(t.Image),
+			Resources: t.Resources,
+		}
+	}
+
+	if s := in.Smee; s != nil {
+		out.Smee = &Smee{
+			Image: Image(s.Image),
+			BackendConfigs: BackendConfigs{
+				BackendKubeMode: (*BackendKubeMode)(s.BackendConfigs.BackendKubeMode),
+				BackendFileMode: (*BackendFileMode)(s.BackendConfigs.BackendFileMode),
+			},
+			SyslogConfigs: (*SyslogConfigs)(s.SyslogConfigs),
+			TFTPConfigs:   (*TFTPConfigs)(s.TFTPConfigs),
+			IPXEConfigs:   (*IPXEConfigs)(s.IPXEConfigs),
+			DHCPConfigs:   (*DHCPConfigs)(s.DHCPConfigs),
+			LogLevel:      s.LogLevel,
+			Resources:     s.Resources,
+		}
+	}
+
+	if h := in.Hegel; h != nil {
+		out.Hegel = &Hegel{
+			Image:          Image(h.Image),
+			TrustedProxies: h.TrustedProxies,
+			Port:           h.Port,
+			Resources:      h.Resources,
+		}
+	}
+
+	if r := in.Rufio; r != nil {
+		out.Rufio = &Rufio{
+			Image:     Image(r.Image),
+			Resources: r.Resources,
+		}
+	}
+
+	return out
+}
+
+func statusToV1alpha2(in StackStatus) v1alpha2.StackStatus {
+	out := v1alpha2.StackStatus{
+		ObservedGeneration: in.ObservedGeneration,
+		PublicIP:           in.PublicIP,
+		ClusterDNS:         in.ClusterDNS,
+		Conditions:         in.Conditions,
+		HookArtifacts:      (*v1alpha2.HookArtifactsStatus)(in.HookArtifacts),
+	}
+
+	if in.Components != nil {
+		out.Components = make([]v1alpha2.ComponentStatus, len(in.Components))
+		for i := range in.Components {
+			out.Components[i] = v1alpha2.ComponentStatus(in.Components[i])
+		}
+	}
+
+	if in.CRDs != nil {
+		out.CRDs = make([]v1alpha2.CRDStatus, len(in.CRDs))
+		for i := range in.CRDs {
+			out.CRDs[i] = v1alpha2.CRDStatus(in.CRDs[i])
+		}
+	}
+
+	return out
+}
+
+func statusFromV1alpha2(in v1alpha2.StackStatus) StackStatus {
+	out := StackStatus{
+		ObservedGeneration: in.ObservedGeneration,
+		PublicIP:           in.PublicIP,
+		ClusterDNS:         in.ClusterDNS,
+		Conditions:         in.Conditions,
+		HookArtifacts:      (*HookArtifactsStatus)(in.HookArtifacts),
+	}
+
+	if in.Components != nil {
+		out.Components = make([]ComponentStatus, len(in.Components))
+		for i := range in.Components {
+			out.Components[i] = ComponentStatus(in.Components[i])
+		}
+	}
+
+	if in.CRDs != nil {
+		out.CRDs = make([]CRDStatus, len(in.CRDs))
+		for i := range in.CRDs {
+			out.CRDs[i] = CRDStatus(in.CRDs[i])
+		}
+	}
+
+	return out
+}
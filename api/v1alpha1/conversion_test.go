@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	"math/rand"
+	"testing"
+
+	fuzz "github.com/google/gofuzz"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+const fuzzIterations = 1000
+
+func newFuzzer(t *testing.T) *fuzz.Fuzzer {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return fuzzer.FuzzerFor(metafuzzer.Funcs, rand.NewSource(rand.Int63()), serializer.NewCodecFactory(scheme))
+}
+
+func TestStackConversionRoundTrip(t *testing.T) {
+	t.Run("v1alpha1 to v1alpha2 and back", func(t *testing.T) {
+		f := newFuzzer(t)
+
+		for i := 0; i < fuzzIterations; i++ {
+			original := &Stack{}
+			f.Fuzz(original)
+
+			hub := &v1alpha2.Stack{}
+			if err := original.DeepCopy().ConvertTo(hub); err != nil {
+				t.Fatalf("failed to convert to v1alpha2: %v", err)
+			}
+
+			converted := &Stack{}
+			if err := converted.ConvertFrom(hub); err != nil {
+				t.Fatalf("failed to convert from v1alpha2: %v", err)
+			}
+
+			if !apiequality.Semantic.DeepEqual(original, converted) {
+				t.Fatalf("round trip changed the stack:\n%s", diff.ObjectReflectDiff(original, converted))
+			}
+		}
+	})
+
+	t.Run("v1alpha2 to v1alpha1 and back", func(t *testing.T) {
+		f := newFuzzer(t)
+
+		for i := 0; i < fuzzIterations; i++ {
+			original := &v1alpha2.Stack{}
+			f.Fuzz(original)
+
+			spoke := &Stack{}
+			if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+				t.Fatalf("failed to convert from v1alpha2: %v", err)
+			}
+
+			converted := &v1alpha2.Stack{}
+			if err := spoke.ConvertTo(converted); err != nil {
+				t.Fatalf("failed to convert to v1alpha2: %v", err)
+			}
+
+			if !apiequality.Semantic.DeepEqual(original, converted) {
+				t.Fatalf("round trip changed the stack:\n%s", diff.ObjectReflectDiff(original, converted))
+			}
+		}
+	})
+}
@@ -1,5 +1,6 @@
 // +k8s:deepcopy-gen:package
 // +k8s:openapi-gen=true
+// +kubebuilder:object:generate=true
 
 // Package v1alpha1 contains API Schema definitions for the Tinkerbell operator v1alpha1 API group
 // +groupName=tinkerbell.org
@@ -22,15 +22,596 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackendConfigs) DeepCopyInto(out *BackendConfigs) {
+	*out = *in
+	if in.BackendKubeMode != nil {
+		in, out := &in.BackendKubeMode, &out.BackendKubeMode
+		*out = new(BackendKubeMode)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BackendFileMode != nil {
+		in, out := &in.BackendFileMode, &out.BackendFileMode
+		*out = new(BackendFileMode)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackendConfigs.
+func (in *BackendConfigs) DeepCopy() *BackendConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(BackendConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackendFileMode) DeepCopyInto(out *BackendFileMode) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackendFileMode.
+func (in *BackendFileMode) DeepCopy() *BackendFileMode {
+	if in == nil {
+		return nil
+	}
+	out := new(BackendFileMode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackendKubeMode) DeepCopyInto(out *BackendKubeMode) {
+	*out = *in
+	if in.KubeConfigFilePath != nil {
+		in, out := &in.KubeConfigFilePath, &out.KubeConfigFilePath
+		*out = new(string)
+		**out = **in
+	}
+	if in.KubeAPIURL != nil {
+		in, out := &in.KubeAPIURL, &out.KubeAPIURL
+		*out = new(string)
+		**out = **in
+	}
+	if in.KubeNamespace != nil {
+		in, out := &in.KubeNamespace, &out.KubeNamespace
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackendKubeMode.
+func (in *BackendKubeMode) DeepCopy() *BackendKubeMode {
+	if in == nil {
+		return nil
+	}
+	out := new(BackendKubeMode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CRDStatus) DeepCopyInto(out *CRDStatus) {
+	*out = *in
+	if in.StoredVersions != nil {
+		in, out := &in.StoredVersions, &out.StoredVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CRDStatus.
+func (in *CRDStatus) DeepCopy() *CRDStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CRDStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManager) DeepCopyInto(out *CertManager) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManager.
+func (in *CertManager) DeepCopy() *CertManager {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManager)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerRef) DeepCopyInto(out *CertManagerIssuerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerRef.
+func (in *CertManagerIssuerRef) DeepCopy() *CertManagerIssuerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
+func (in *ComponentStatus) DeepCopy() *ComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DHCPConfigs) DeepCopyInto(out *DHCPConfigs) {
+	*out = *in
+	if in.IPForPacket != nil {
+		in, out := &in.IPForPacket, &out.IPForPacket
+		*out = new(string)
+		**out = **in
+	}
+	if in.SyslogIP != nil {
+		in, out := &in.SyslogIP, &out.SyslogIP
+		*out = new(string)
+		**out = **in
+	}
+	if in.TFTPAddress != nil {
+		in, out := &in.TFTPAddress, &out.TFTPAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPIPXEBinaryAddress != nil {
+		in, out := &in.HTTPIPXEBinaryAddress, &out.HTTPIPXEBinaryAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.HTTPIPXEScriptURI != nil {
+		in, out := &in.HTTPIPXEScriptURI, &out.HTTPIPXEScriptURI
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DHCPConfigs.
+func (in *DHCPConfigs) DeepCopy() *DHCPConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(DHCPConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hegel) DeepCopyInto(out *Hegel) {
+	*out = *in
+	out.Image = in.Image
+	if in.TrustedProxies != nil {
+		in, out := &in.TrustedProxies, &out.TrustedProxies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hegel.
+func (in *Hegel) DeepCopy() *Hegel {
+	if in == nil {
+		return nil
+	}
+	out := new(Hegel)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookArtifacts) DeepCopyInto(out *HookArtifacts) {
+	*out = *in
+	if in.Architectures != nil {
+		in, out := &in.Architectures, &out.Architectures
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(HookArtifactsSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Checksums != nil {
+		in, out := &in.Checksums, &out.Checksums
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Storage != nil {
+		in, out := &in.Storage, &out.Storage
+		*out = new(HookStorage)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookArtifacts.
+func (in *HookArtifacts) DeepCopy() *HookArtifacts {
+	if in == nil {
+		return nil
+	}
+	out := new(HookArtifacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookArtifactsSource) DeepCopyInto(out *HookArtifactsSource) {
+	*out = *in
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(HookImageSource)
+		**out = **in
+	}
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(HookVolumeSource)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookArtifactsSource.
+func (in *HookArtifactsSource) DeepCopy() *HookArtifactsSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HookArtifactsSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookArtifactsStatus) DeepCopyInto(out *HookArtifactsStatus) {
+	*out = *in
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookArtifactsStatus.
+func (in *HookArtifactsStatus) DeepCopy() *HookArtifactsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HookArtifactsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookHostPath) DeepCopyInto(out *HookHostPath) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookHostPath.
+func (in *HookHostPath) DeepCopy() *HookHostPath {
+	if in == nil {
+		return nil
+	}
+	out := new(HookHostPath)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookImageSource) DeepCopyInto(out *HookImageSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookImageSource.
+func (in *HookImageSource) DeepCopy() *HookImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HookImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookPersistentVolumeClaim) DeepCopyInto(out *HookPersistentVolumeClaim) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookPersistentVolumeClaim.
+func (in *HookPersistentVolumeClaim) DeepCopy() *HookPersistentVolumeClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(HookPersistentVolumeClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookStorage) DeepCopyInto(out *HookStorage) {
+	*out = *in
+	if in.PersistentVolumeClaim != nil {
+		in, out := &in.PersistentVolumeClaim, &out.PersistentVolumeClaim
+		*out = new(HookPersistentVolumeClaim)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostPath != nil {
+		in, out := &in.HostPath, &out.HostPath
+		*out = new(HookHostPath)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookStorage.
+func (in *HookStorage) DeepCopy() *HookStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(HookStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookVolumeSource) DeepCopyInto(out *HookVolumeSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookVolumeSource.
+func (in *HookVolumeSource) DeepCopy() *HookVolumeSource {
+	if in == nil {
+		return nil
+	}
+	out := new(HookVolumeSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IPXEConfigs) DeepCopyInto(out *IPXEConfigs) {
+	*out = *in
+	if in.TinkServerAddress != nil {
+		in, out := &in.TinkServerAddress, &out.TinkServerAddress
+		*out = new(string)
+		**out = **in
+	}
+	if in.EnableHTTPBinary != nil {
+		in, out := &in.EnableHTTPBinary, &out.EnableHTTPBinary
+		*out = new(bool)
+		**out = **in
+	}
+	if in.EnableTLS != nil {
+		in, out := &in.EnableTLS, &out.EnableTLS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ExtraKernelArgs != nil {
+		in, out := &in.ExtraKernelArgs, &out.ExtraKernelArgs
+		*out = new(string)
+		**out = **in
+	}
+	if in.HookURL != nil {
+		in, out := &in.HookURL, &out.HookURL
+		*out = new(string)
+		**out = **in
+	}
+	if in.TrustedProxies != nil {
+		in, out := &in.TrustedProxies, &out.TrustedProxies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IPXEConfigs.
+func (in *IPXEConfigs) DeepCopy() *IPXEConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(IPXEConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Image) DeepCopyInto(out *Image) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Image.
+func (in *Image) DeepCopy() *Image {
+	if in == nil {
+		return nil
+	}
+	out := new(Image)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PublicAddress) DeepCopyInto(out *PublicAddress) {
+	*out = *in
+	if in.IP != nil {
+		in, out := &in.IP, &out.IP
+		*out = new(string)
+		**out = **in
+	}
+	if in.LoadBalancerService != nil {
+		in, out := &in.LoadBalancerService, &out.LoadBalancerService
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PublicAddress.
+func (in *PublicAddress) DeepCopy() *PublicAddress {
+	if in == nil {
+		return nil
+	}
+	out := new(PublicAddress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Rufio) DeepCopyInto(out *Rufio) {
+	*out = *in
+	out.Image = in.Image
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Rufio.
+func (in *Rufio) DeepCopy() *Rufio {
+	if in == nil {
+		return nil
+	}
+	out := new(Rufio)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Services) DeepCopyInto(out *Services) {
+	*out = *in
+	if in.Smee != nil {
+		in, out := &in.Smee, &out.Smee
+		*out = new(Smee)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hegel != nil {
+		in, out := &in.Hegel, &out.Hegel
+		*out = new(Hegel)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rufio != nil {
+		in, out := &in.Rufio, &out.Rufio
+		*out = new(Rufio)
+		(*in).DeepCopyInto(*out)
+	}
+	in.TinkServer.DeepCopyInto(&out.TinkServer)
+	in.TinkController.DeepCopyInto(&out.TinkController)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Services.
+func (in *Services) DeepCopy() *Services {
+	if in == nil {
+		return nil
+	}
+	out := new(Services)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Smee) DeepCopyInto(out *Smee) {
+	*out = *in
+	out.Image = in.Image
+	in.BackendConfigs.DeepCopyInto(&out.BackendConfigs)
+	if in.SyslogConfigs != nil {
+		in, out := &in.SyslogConfigs, &out.SyslogConfigs
+		*out = new(SyslogConfigs)
+		**out = **in
+	}
+	if in.TFTPConfigs != nil {
+		in, out := &in.TFTPConfigs, &out.TFTPConfigs
+		*out = new(TFTPConfigs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IPXEConfigs != nil {
+		in, out := &in.IPXEConfigs, &out.IPXEConfigs
+		*out = new(IPXEConfigs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DHCPConfigs != nil {
+		in, out := &in.DHCPConfigs, &out.DHCPConfigs
+		*out = new(DHCPConfigs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LogLevel != nil {
+		in, out := &in.LogLevel, &out.LogLevel
+		*out = new(string)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Smee.
+func (in *Smee) DeepCopy() *Smee {
+	if in == nil {
+		return nil
+	}
+	out := new(Smee)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Stack) DeepCopyInto(out *Stack) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Stack.
@@ -50,3 +631,224 @@ func (in *Stack) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackList) DeepCopyInto(out *StackList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Stack, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackList.
+func (in *StackList) DeepCopy() *StackList {
+	if in == nil {
+		return nil
+	}
+	out := new(StackList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *StackList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackSpec) DeepCopyInto(out *StackSpec) {
+	*out = *in
+	in.Services.DeepCopyInto(&out.Services)
+	if in.DNSResolverIP != nil {
+		in, out := &in.DNSResolverIP, &out.DNSResolverIP
+		*out = new(string)
+		**out = **in
+	}
+	if in.PublicAddress != nil {
+		in, out := &in.PublicAddress, &out.PublicAddress
+		*out = new(PublicAddress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookArtifacts != nil {
+		in, out := &in.HookArtifacts, &out.HookArtifacts
+		*out = new(HookArtifacts)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Registry != nil {
+		in, out := &in.Registry, &out.Registry
+		*out = new(string)
+		**out = **in
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStrategy)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackSpec.
+func (in *StackSpec) DeepCopy() *StackSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StackSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackStatus) DeepCopyInto(out *StackStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HookArtifacts != nil {
+		in, out := &in.HookArtifacts, &out.HookArtifacts
+		*out = new(HookArtifactsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CRDs != nil {
+		in, out := &in.CRDs, &out.CRDs
+		*out = make([]CRDStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackStatus.
+func (in *StackStatus) DeepCopy() *StackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyslogConfigs) DeepCopyInto(out *SyslogConfigs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyslogConfigs.
+func (in *SyslogConfigs) DeepCopy() *SyslogConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(SyslogConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TFTPConfigs) DeepCopyInto(out *TFTPConfigs) {
+	*out = *in
+	if in.TFTPTimeout != nil {
+		in, out := &in.TFTPTimeout, &out.TFTPTimeout
+		*out = new(int)
+		**out = **in
+	}
+	if in.IPXEScriptPatch != nil {
+		in, out := &in.IPXEScriptPatch, &out.IPXEScriptPatch
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TFTPConfigs.
+func (in *TFTPConfigs) DeepCopy() *TFTPConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(TFTPConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TinkController) DeepCopyInto(out *TinkController) {
+	*out = *in
+	out.Image = in.Image
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TinkController.
+func (in *TinkController) DeepCopy() *TinkController {
+	if in == nil {
+		return nil
+	}
+	out := new(TinkController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TinkServer) DeepCopyInto(out *TinkServer) {
+	*out = *in
+	out.Image = in.Image
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManager)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TinkServer.
+func (in *TinkServer) DeepCopy() *TinkServer {
+	if in == nil {
+		return nil
+	}
+	out := new(TinkServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStrategy) DeepCopyInto(out *UpgradeStrategy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStrategy.
+func (in *UpgradeStrategy) DeepCopy() *UpgradeStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,4 @@
+package v1alpha2
+
+// Hub marks v1alpha2 as the version all the other Stack versions are converted through. It is the storage version.
+func (*Stack) Hub() {}
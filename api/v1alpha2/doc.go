@@ -0,0 +1,7 @@
+// +k8s:deepcopy-gen:package
+// +k8s:openapi-gen=true
+// +kubebuilder:object:generate=true
+
+// Package v1alpha2 contains API Schema definitions for the Tinkerbell operator v1alpha2 API group
+// +groupName=tinkerbell.org
+package v1alpha2
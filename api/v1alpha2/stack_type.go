@@ -0,0 +1,726 @@
+package v1alpha2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=stack,scope=Namespaced,categories=stack,singular=stack
+// +kubebuilder:storageversion
+// +kubebuilder:storageversion
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Progressing",type=string,JSONPath=`.status.conditions[?(@.type=="Progressing")].status`
+// +kubebuilder:printcolumn:name="Degraded",type=string,JSONPath=`.status.conditions[?(@.type=="Degraded")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Stack represents the tinkerbell stack that is being deployed in the kubernetes where the operator is deployed.
+// Tinkerbell operator watches for different resources such as deployment, services, serviceAccounts, etc. One of those
+// CRs is Stack which the operator will install the tink-stack based on its specs. Once the CR is deleted,
+// the operator will delete all tinkerbell resources.
+type Stack struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the desired tinkerbell stack state.
+	Spec StackSpec `json:"spec"`
+
+	// Status contains information about the reconciliation status.
+	// +optional
+	Status StackStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StackList contains a list of Stack.
+type StackList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Stack `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Stack{}, &StackList{})
+}
+
+// StackSpec specifies details of the Tinkerbell setup.
+type StackSpec struct {
+	// Version is the Tinkerbell release version. It selects the compatible images of all the stack components and
+	// Hook from the operator release matrix. The images of single components can still be overridden through their
+	// image spec.
+	Version string `json:"version"`
+
+	// Services contains all Tinkerbell Stack services. Defaults to tink-server and tink-controller.
+	// +optional
+	Services Services `json:"services,omitempty"`
+
+	// DNSResolverIP is indicative of the resolver IP utilized for setting up the nginx server responsible for proxying
+	// to the Tinkerbell services and serving the Hook artifacts. It takes precedence over the operator --cluster-dns
+	// flag. If neither is set, the resolver is discovered from the cluster DNS Service.
+	// +optional
+	DNSResolverIP *string `json:"dnsResolverIP,omitempty"`
+
+	// PublicAddress configures the address the provisioned machines use to reach the Tinkerbell services, e.g. to get
+	// an IP address via DHCP, to download Hook and to connect to tink-server. If it is not set, the address is discovered
+	// from the node the nginx proxy is running on.
+	// +optional
+	PublicAddress *PublicAddress `json:"publicAddress,omitempty"`
+
+	// HookArtifacts specifies the Hook artifacts the nginx proxy serves to the provisioned machines. Defaults to the
+	// Hook release of the Stack version for all the supported architectures.
+	// +optional
+	HookArtifacts *HookArtifacts `json:"hookArtifacts,omitempty"`
+
+	// Nginx customizes the nginx proxy which exposes the Tinkerbell services and serves the Hook artifacts.
+	// +optional
+	Nginx *Nginx `json:"nginx,omitempty"`
+
+	// Registry is the registry to use for all images. If this field is set, the registry of all the images deployed
+	// by the operator, including the Hook download jobs, is replaced with this value. For example if the value here was set
+	// to registry.local, then smee image will be registry.local/tinkerbell/smee. The operator --overwrite-registry flag
+	// takes precedence over this field.
+	// +optional
+	Registry *string `json:"registry,omitempty"`
+
+	// ImagePullSecrets the secret name containing the docker auth config which should exist in the same namespace where
+	// the operator is deployed(typically tinkerbell)
+	// +optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// CRDDeletionPolicy specifies whether the Tinkerbell CRDs are deleted along with the Stack. Deleting the CRDs
+	// deletes all the Hardware, Template, Workflow and BMC objects as well. Defaults to Retain.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +kubebuilder:default=Retain
+	// +optional
+	CRDDeletionPolicy CRDDeletionPolicy `json:"crdDeletionPolicy,omitempty"`
+
+	// Airgapped makes sure the stack doesn't reference any public source. All the images are pulled from the Registry
+	// and the Hook artifacts are loaded from their Source or an internal URL. The operator doesn't deploy an airgapped
+	// stack until both are configured, the Airgapped condition explains what is missing.
+	// +optional
+	Airgapped bool `json:"airgapped,omitempty"`
+
+	// Upgrade configures how changes of the stack components, e.g. a new Version, are rolled out.
+	// +optional
+	Upgrade *UpgradeStrategy `json:"upgrade,omitempty"`
+}
+
+// UpgradeStrategy configures the rollout of the stack components. The components are rolled out one after another in
+// their dependency order: tink-server, tink-controller, Hegel, Rufio, Smee and nginx. A component is only rolled out
+// once the ones before it are available, a failed rollout halts the upgrade and marks the stack Degraded.
+type UpgradeStrategy struct {
+	// AutoRollback rolls a component back to its previous revision if its rollout fails. The failed configuration is
+	// not applied again until the Stack or the cluster configuration changes.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+// CRDDeletionPolicy specifies what happens to the Tinkerbell CRDs when the Stack is deleted.
+type CRDDeletionPolicy string
+
+const (
+	// CRDDeletionPolicyRetain keeps the Tinkerbell CRDs and their objects when the Stack is deleted.
+	CRDDeletionPolicyRetain CRDDeletionPolicy = "Retain"
+	// CRDDeletionPolicyDelete deletes the Tinkerbell CRDs and their objects when the Stack is deleted.
+	CRDDeletionPolicyDelete CRDDeletionPolicy = "Delete"
+)
+
+// HookArtifacts specifies the Hook artifacts the nginx proxy serves to the provisioned machines.
+type HookArtifacts struct {
+	// Version is the Hook release version. Defaults to the Hook version of the Stack release.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Architectures are the architectures the Hook artifacts are published for. Defaults to x86_64 and aarch64.
+	// +optional
+	Architectures []string `json:"architectures,omitempty"`
+
+	// URL is the base URL the hook_<architecture>.tar.gz archives are downloaded from, e.g. an internal file server.
+	// Defaults to the GitHub release of the Hook version. It is ignored if Source is set.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Source loads the hook_<architecture>.tar.gz archives from a local OCI image or PersistentVolumeClaim instead of
+	// downloading them from the URL.
+	// +optional
+	Source *HookArtifactsSource `json:"source,omitempty"`
+
+	// Checksums maps the artifact file names, e.g. vmlinuz-x86_64 and initramfs-x86_64, to their sha512 checksums.
+	// Defaults to the checksums of the Stack release if the Hook version matches it, otherwise the checksums of all the
+	// artifacts are required.
+	// +optional
+	Checksums map[string]string `json:"checksums,omitempty"`
+
+	// Storage specifies the volume the Hook artifacts are stored in and served from. Defaults to the /opt/hook host
+	// path of the node the nginx proxy is running on.
+	// +optional
+	Storage *HookStorage `json:"storage,omitempty"`
+
+	// Download customizes the containers which download and publish the Hook artifacts. The resources default to 64Mi
+	// memory and 10m CPU requests and 256Mi memory and 500m CPU limits. The other settings apply to the download Jobs,
+	// the EmptyDir storage is populated by init containers of the nginx pods instead. Replicas must not be set.
+	// +optional
+	Download *ComponentDeployment `json:"download,omitempty"`
+}
+
+// Nginx customizes the nginx proxy.
+type Nginx struct {
+	// ComponentDeployment customizes the Kubernetes deployment of the nginx proxy. More than one replica requires every
+	// replica to see the Hook artifacts, i.e. the EmptyDir storage or a ReadWriteMany PersistentVolumeClaim.
+	ComponentDeployment `json:",inline"`
+}
+
+// HookArtifactsSource specifies a local source of the Hook archives. Exactly one of the sources must be set.
+type HookArtifactsSource struct {
+	// Image is an OCI image containing the Hook archives. The image is pulled from the Stack registry if one is set.
+	// +optional
+	Image *HookImageSource `json:"image,omitempty"`
+
+	// PersistentVolumeClaim is a claim in the stack namespace containing the Hook archives.
+	// +optional
+	PersistentVolumeClaim *HookVolumeSource `json:"persistentVolumeClaim,omitempty"`
+}
+
+// HookImageSource specifies an OCI image containing the Hook archives.
+type HookImageSource struct {
+	// Image is the reference of the image, e.g. registry.local/tinkerbell/hook-bundle:v0.8.1.
+	Image string `json:"image"`
+
+	// Path is the directory of the archives in the image. Defaults to /hook.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// HookVolumeSource specifies a PersistentVolumeClaim containing the Hook archives.
+type HookVolumeSource struct {
+	// ClaimName is the name of the claim in the stack namespace.
+	ClaimName string `json:"claimName"`
+
+	// Path is the directory of the archives in the volume. Defaults to the root of the volume.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// HookStorage specifies the volume the Hook artifacts are stored in.
+type HookStorage struct {
+	// Type is the type of the volume. PersistentVolumeClaim stores the artifacts in a claim managed by the operator,
+	// which lets the nginx proxy reschedule across nodes and, with a ReadWriteMany access mode, run more than one
+	// replica. EmptyDir downloads the artifacts into every nginx pod. HostPath stores the artifacts on the node the
+	// nginx proxy is running on. Defaults to HostPath.
+	// +kubebuilder:validation:Enum=PersistentVolumeClaim;EmptyDir;HostPath
+	// +kubebuilder:default=HostPath
+	// +optional
+	Type HookStorageType `json:"type,omitempty"`
+
+	// PersistentVolumeClaim configures the claim of the PersistentVolumeClaim storage.
+	// +optional
+	PersistentVolumeClaim *HookPersistentVolumeClaim `json:"persistentVolumeClaim,omitempty"`
+
+	// HostPath configures the host path of the HostPath storage.
+	// +optional
+	HostPath *HookHostPath `json:"hostPath,omitempty"`
+}
+
+// HookStorageType is the type of the volume the Hook artifacts are stored in.
+type HookStorageType string
+
+const (
+	// HookStorageTypePersistentVolumeClaim stores the Hook artifacts in a PersistentVolumeClaim.
+	HookStorageTypePersistentVolumeClaim HookStorageType = "PersistentVolumeClaim"
+	// HookStorageTypeEmptyDir stores the Hook artifacts in an emptyDir volume of every nginx pod.
+	HookStorageTypeEmptyDir HookStorageType = "EmptyDir"
+	// HookStorageTypeHostPath stores the Hook artifacts in a host path of the node the nginx proxy is running on.
+	HookStorageTypeHostPath HookStorageType = "HostPath"
+)
+
+// HookPersistentVolumeClaim configures the PersistentVolumeClaim the Hook artifacts are stored in.
+type HookPersistentVolumeClaim struct {
+	// StorageClassName is the storage class of the claim. Defaults to the default storage class of the cluster.
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size is the requested size of the claim. Defaults to 2Gi.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// AccessMode is the access mode of the claim. With ReadWriteMany the artifacts are published independently of
+	// the nodes the nginx pods are running on. Defaults to ReadWriteOnce.
+	// +kubebuilder:validation:Enum=ReadWriteOnce;ReadWriteMany
+	// +kubebuilder:default=ReadWriteOnce
+	// +optional
+	AccessMode corev1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
+}
+
+// HookHostPath configures the host path the Hook artifacts are stored in.
+type HookHostPath struct {
+	// Path is the directory on the node. Defaults to /opt/hook.
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
+// PublicAddress configures the address the provisioned machines use to reach the Tinkerbell services.
+type PublicAddress struct {
+	// IP is the public IP of the stack. It takes precedence over the discovery.
+	// +optional
+	IP *string `json:"ip,omitempty"`
+
+	// Discovery specifies how the public IP is discovered if IP is not set. NginxNode uses the address of the node the
+	// nginx proxy is running on, the oldest ready pod with several replicas. LoadBalancer uses the ingress IP of the
+	// LoadBalancerService. Defaults to NginxNode.
+	// +kubebuilder:validation:Enum=NginxNode;LoadBalancer
+	// +kubebuilder:default=NginxNode
+	// +optional
+	Discovery PublicAddressDiscovery `json:"discovery,omitempty"`
+
+	// LoadBalancerService is the name of the LoadBalancer Service in the stack namespace whose ingress IP is used as
+	// public IP. It is required by the LoadBalancer discovery.
+	// +optional
+	LoadBalancerService *string `json:"loadBalancerService,omitempty"`
+}
+
+// PublicAddressDiscovery specifies how the public address of the stack is discovered.
+type PublicAddressDiscovery string
+
+const (
+	// PublicAddressDiscoveryNginxNode discovers the public address from the node the nginx proxy is running on.
+	PublicAddressDiscoveryNginxNode PublicAddressDiscovery = "NginxNode"
+	// PublicAddressDiscoveryLoadBalancer discovers the public address from the ingress IP of a LoadBalancer Service.
+	PublicAddressDiscoveryLoadBalancer PublicAddressDiscovery = "LoadBalancer"
+)
+
+const (
+	// ConditionAvailable indicates that the component deployment has the minimum number of replicas available.
+	ConditionAvailable = "Available"
+	// ConditionProgressing indicates that the component deployment is rolling out a new revision.
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded indicates that the component deployment is missing or failed to roll out.
+	ConditionDegraded = "Degraded"
+	// ConditionReady indicates that the Hook artifacts are verified and published.
+	ConditionReady = "Ready"
+	// ConditionAirgapped indicates that an airgapped stack has all its images and Hook artifacts available from local
+	// sources.
+	ConditionAirgapped = "Airgapped"
+	// ConditionCRDsEstablished indicates that the Tinkerbell CRDs of the Stack version are installed and served.
+	ConditionCRDsEstablished = "CRDsEstablished"
+	// ConditionHostPortsAvailable indicates that the host ports Smee listens on are not claimed by the Smee of an older
+	// Stack of the cluster.
+	ConditionHostPortsAvailable = "HostPortsAvailable"
+	// ConditionReconciled indicates whether the operator managed to reconcile the Stack, e.g. it is false if the Stack
+	// version is unknown.
+	ConditionReconciled = "Reconciled"
+)
+
+// StackStatus contains information about the reconciliation status of the Tinkerbell stack.
+type StackStatus struct {
+	// ObservedGeneration is the most recent Stack generation observed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// PublicIP is the public IP of the stack the Tinkerbell services are configured with, either set in the spec or
+	// discovered.
+	// +optional
+	PublicIP string `json:"publicIP,omitempty"`
+	// ClusterDNS is the cluster DNS resolver the nginx proxy is configured with, either set or discovered.
+	// +optional
+	ClusterDNS string `json:"clusterDNS,omitempty"`
+	// Conditions summarize the state of all the stack components.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// HookArtifacts contains the state of the Hook artifacts the nginx proxy serves.
+	// +optional
+	HookArtifacts *HookArtifactsStatus `json:"hookArtifacts,omitempty"`
+	// Components contains the status of each of the stack components.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+	// CRDs contains the state of the Tinkerbell CRDs the operator installs for the Stack version.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	CRDs []CRDStatus `json:"crds,omitempty"`
+}
+
+// CRDStatus contains the state of a Tinkerbell CRD.
+type CRDStatus struct {
+	// Name is the name of the CRD, e.g. hardware.tinkerbell.org.
+	Name string `json:"name"`
+	// StorageVersion is the version the objects of the CRD are stored in.
+	// +optional
+	StorageVersion string `json:"storageVersion,omitempty"`
+	// StoredVersions are all the versions objects of the CRD have ever been stored in.
+	// +optional
+	StoredVersions []string `json:"storedVersions,omitempty"`
+	// Established is whether the API server serves the CRD.
+	Established bool `json:"established"`
+}
+
+// HookArtifactsStatus contains the state of the Hook artifacts the nginx proxy serves.
+type HookArtifactsStatus struct {
+	// Version is the Hook version of the artifacts.
+	// +optional
+	Version string `json:"version,omitempty"`
+	// Node is the node the artifacts are published on.
+	// +optional
+	Node string `json:"node,omitempty"`
+	// Artifacts are the file names of the artifacts.
+	// +optional
+	Artifacts []string `json:"artifacts,omitempty"`
+	// Conditions contains the Ready condition of the artifacts, which explains the download progress or failures.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ComponentStatus contains the status of a single Tinkerbell stack component.
+type ComponentStatus struct {
+	// Name is the name of the component, e.g. smee or tink-server.
+	Name string `json:"name"`
+	// Image is the image the component deployment is running with.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Conditions contains the Available, Progressing and Degraded conditions of the component.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// Services contains all Tinkerbell Stack services.
+type Services struct {
+	// Smee contains all the information and spec about smee. Smee is not deployed if it is not set, which allows using
+	// an external DHCP server.
+	// +optional
+	Smee *Smee `json:"smee,omitempty"`
+
+	// Hegel contains all the information and spec about hegel. Hegel is not deployed if it is not set.
+	// +optional
+	Hegel *Hegel `json:"hegel,omitempty"`
+
+	// Rufio contains all the information and spec about rufio. Rufio is not deployed if it is not set.
+	// +optional
+	Rufio *Rufio `json:"rufio,omitempty"`
+
+	// TinkServer contains all the information and spec about tink server. tink-server is always deployed, it is
+	// deployed with the defaults if it is not set.
+	// +optional
+	TinkServer *TinkServer `json:"tinkServer,omitempty"`
+
+	// TinkController contains all the information and spec about tink controller. tink-controller is always deployed,
+	// it is deployed with the defaults if it is not set.
+	// +optional
+	TinkController *TinkController `json:"tinkController,omitempty"`
+}
+
+// Smee specifies the deployment details of Tinkerbell service, Smee.
+type Smee struct {
+	// Image specifies the image repo and tag for Smee.
+	// +optional
+	Image Image `json:"image,omitempty"`
+
+	// BackendConfigs contains the configurations for smee backend. Defaults to the Kubernetes backend.
+	// +optional
+	BackendConfigs BackendConfigs `json:"backendConfigs,omitempty"`
+
+	// SyslogConfigs contains the configurations of the syslog server.
+	// +optional
+	SyslogConfigs *SyslogConfigs `json:"syslogConfigs,omitempty"`
+
+	// TFTPConfigs contains the configurations of Tinkerbell TFTP server.
+	// +optional
+	TFTPConfigs *TFTPConfigs `json:"tftpConfigs,omitempty"`
+
+	// IPXEConfigs contains the iPXE configurations.
+	// +optional
+	IPXEConfigs *IPXEConfigs `json:"ipxeConfigs,omitempty"`
+
+	// DHCPConfigs contains the DHCP server configurations.
+	// +optional
+	DHCPConfigs *DHCPConfigs `json:"dhcpConfigs,omitempty"`
+
+	// LogLevel sets the debug level for smee.
+	// +optional
+	LogLevel *string `json:"logLevel,omitempty"`
+
+	// ComponentDeployment customizes the Kubernetes deployment of smee.
+	ComponentDeployment `json:",inline"`
+}
+
+// SyslogConfigs contains the configurations of the syslog server.
+type SyslogConfigs struct {
+	// IP is the local IP to listen on for syslog messages. Defaults to 0.0.0.0.
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Port is the local port to listen on for syslog messages. Defaults to 514.
+	// +optional
+	Port int `json:"port,omitempty"`
+}
+
+// TFTPConfigs contains the configurations of Tinkerbell TFTP server.
+type TFTPConfigs struct {
+	// IP is the local IP to listen on to serve TFTP binaries. Defaults to 0.0.0.0.
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Port is the local port to listen on to serve TFTP binaries. Defaults to 69.
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// TFTPTimeout specifies the iPXE tftp binary server requests timeout.
+	// +optional
+	TFTPTimeout *int `json:"tftpTimeout,omitempty"`
+
+	// IPXEScriptPatch specifies the iPXE script fragment to patch into served iPXE binaries served via TFTP or HTTP.
+	// +optional
+	IPXEScriptPatch *string `json:"ipxeScriptPatch,omitempty"`
+}
+
+// IPXEConfigs contains the iPXE configurations.
+type IPXEConfigs struct {
+	// IP is the local IP to listen on to serve the iPXE binaries and scripts. Defaults to 0.0.0.0.
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Port is the local port to listen on to serve the iPXE binaries and scripts. Defaults to 80.
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// TinkServerAddress specifies the IP:Port of the tink server.
+	// +optional
+	TinkServerAddress *string `json:"tinkServerAddress,omitempty"`
+
+	// EnableHTTPBinary enable iPXE HTTP binary server.
+	// +optional
+	EnableHTTPBinary *bool `json:"enableHTTPBinary,omitempty"`
+
+	// EnableTLS sets if the smee should run with TLS or not.
+	// +optional
+	EnableTLS *bool `json:"enableTLS,omitempty"`
+
+	// ExtraKernelArgs specifies extra set of kernel args (k=v k=v) that are appended to the kernel cmdline iPXE script.
+	// +optional
+	ExtraKernelArgs *string `json:"extraKernelArgs,omitempty"`
+
+	// HookURL specifies the URL where OSIE(Hook) images are located.
+	// +optional
+	HookURL *string `json:"hookURL,omitempty"`
+
+	// TrustedProxies comma separated allowed CIDRs subnets to be used as trusted proxies. Defaults to the pod CIDRs of
+	// the cluster nodes.
+	// +optional
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+}
+
+// DHCPConfigs contains the DHCP server configurations.
+type DHCPConfigs struct {
+	// IP is the local IP to listen on for DHCP requests. Defaults to 0.0.0.0.
+	// +optional
+	IP string `json:"ip,omitempty"`
+
+	// Port is the local port to listen on for DHCP requests. Defaults to 67.
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// IPForPacket is the IP address to use in DHCP packets.
+	// +optional
+	IPForPacket *string `json:"ipForPacket,omitempty"`
+
+	// SyslogIP specifies the syslog server IP address to use in DHCP packets.
+	// +optional
+	SyslogIP *string `json:"syslogIP,omitempty"`
+
+	// TFTPAddress specifies the tftp server address to use in DHCP packets.
+	// +optional
+	TFTPAddress *string `json:"tftpAddress,omitempty"`
+
+	// HTTPIPXEBinaryAddress specifies the http ipxe binary server address (IP:Port) to use in DHCP packets.
+	// +optional
+	HTTPIPXEBinaryAddress *string `json:"httpIPXEBinaryAddress,omitempty"`
+
+	// HTTPIPXEScriptURI specifies the http ipxe script server URL to use in DHCP packets.
+	// +optional
+	HTTPIPXEScriptURI *string `json:"httpIPXEScriptURI,omitempty"`
+}
+
+// BackendConfigs contains the configurations for smee backend. The Backend has two modes, BackendKubeMode and BackendFileMode.
+// Those modes are mutually exclusive with the BackendKubeMode being the default one. Users must choose one of the two modes.
+type BackendConfigs struct {
+	// BackendKubeMode contains the Kubernetes backend configurations for DHCP and the HTTP iPXE script.
+	// +optional
+	BackendKubeMode *BackendKubeMode `json:"backendKubeMode,omitempty"`
+
+	// BackendFileMode contains the file backend configurations for DHCP and the HTTP iPXE script.
+	// +optional
+	BackendFileMode *BackendFileMode `json:"backendFileMode,omitempty"`
+}
+
+// BackendKubeMode contains the Kubernetes backend configurations for DHCP and the HTTP iPXE script.
+type BackendKubeMode struct {
+	// ConfigFilePath specifies the Kubernetes config file location.
+	// +optional
+	KubeConfigFilePath *string `json:"configFilePath,omitempty"`
+
+	// KubeAPIURL specifies the Kubernetes API URL, used for in-cluster client construction.
+	// +optional
+	KubeAPIURL *string `json:"kubeAPIURL,omitempty"`
+
+	// KubeNamespace specifies an optional Kubernetes namespace override to query hardware data from.
+	// +optional
+	KubeNamespace *string `json:"kubeNamespace,omitempty"`
+}
+
+// BackendFileMode contains the file backend configurations for DHCP and the HTTP iPXE script
+type BackendFileMode struct {
+	// FilePath specifies the hardware yaml file path for the file backend.
+	FilePath string `json:"filePath"`
+}
+
+// Hegel specifies the details of tinkerbell service hegel.
+type Hegel struct {
+	// Image specifies the details of a tinkerbell services images
+	// +optional
+	Image Image `json:"image,omitempty"`
+
+	// TrustedProxies comma separated allowed CIDRs subnets to be used as trusted proxies. Defaults to the pod CIDRs of
+	// the cluster nodes.
+	// +optional
+	TrustedProxies []string `json:"trustedProxies,omitempty"`
+
+	// Port is the port hegel serves the instance metadata on. Defaults to 50061.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// ComponentDeployment customizes the Kubernetes deployment of hegel.
+	ComponentDeployment `json:",inline"`
+}
+
+// Rufio specifies the details of tinkerbell service rufio.
+type Rufio struct {
+	// Image specifies the details of a tinkerbell services images
+	// +optional
+	Image Image `json:"image,omitempty"`
+
+	// ComponentDeployment customizes the Kubernetes deployment of rufio.
+	ComponentDeployment `json:",inline"`
+}
+
+// TinkServer specifies the details of tinkerbell service tink server.
+type TinkServer struct {
+	// Image specifies the details of a tinkerbell services images
+	// +optional
+	Image Image `json:"image,omitempty"`
+
+	// EnableTLS sets if the tink server should run with TLS or not. The operator generates a CA and a serving
+	// certificate for tink-server and rotates them before they expire, unless the certificate is issued by cert-manager.
+	// +optional
+	EnableTLS bool `json:"enableTLS,omitempty"`
+
+	// CertManager issues the tink-server serving certificate with cert-manager instead of the operator. It only takes
+	// effect if TLS is enabled and the cert-manager Certificate CRD is installed in the cluster.
+	// +optional
+	CertManager *CertManager `json:"certManager,omitempty"`
+
+	// Port is the port tink-server serves its gRPC API on. Defaults to 42113.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// ComponentDeployment customizes the Kubernetes deployment of tink-server.
+	ComponentDeployment `json:",inline"`
+}
+
+// CertManager contains the configurations to issue a certificate with cert-manager.
+type CertManager struct {
+	// IssuerRef references the cert-manager issuer of the certificate.
+	IssuerRef CertManagerIssuerRef `json:"issuerRef"`
+}
+
+// CertManagerIssuerRef references a cert-manager issuer.
+type CertManagerIssuerRef struct {
+	// Name is the name of the issuer.
+	Name string `json:"name"`
+
+	// Kind is the kind of the issuer, either Issuer or ClusterIssuer. Defaults to Issuer.
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Group is the API group of the issuer. Defaults to cert-manager.io.
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// TinkController specifies the details of tinkerbell service tink controller.
+type TinkController struct {
+	// Image specifies the details of a tinkerbell services images
+	// +optional
+	Image Image `json:"image,omitempty"`
+
+	// ComponentDeployment customizes the Kubernetes deployment of tink-controller.
+	ComponentDeployment `json:",inline"`
+}
+
+// ComponentDeployment customizes the Kubernetes deployment of a stack component.
+type ComponentDeployment struct {
+	// Resources are the compute resources of the component container. Defaults to 64Mi memory and 10m CPU requests
+	// and 128Mi memory and 500m CPU limits.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Replicas is the number of pods of the component. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// NodeSelector restricts the nodes the component pods are scheduled on, e.g. to the nodes attached to the
+	// provisioning network.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are the tolerations of the component pods.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is the scheduling affinity of the component pods.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints spread the component pods across the topology domains of the cluster.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PriorityClassName is the priority class of the component pods.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Labels are added to the deployment and the pods of the component. They don't override the labels the operator
+	// sets.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the deployment and the pods of the component. They don't override the annotations the
+	// operator sets.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Image specifies the details of a tinkerbell services images.
+type Image struct {
+	// Repository is used to set the image repository for tinkerbell services.
+	// +optional
+	Repository string `json:"repository,omitempty"`
+
+	// Tag is used to set the image tag for tinkerbell services.
+	// +optional
+	Tag string `json:"tag,omitempty"`
+}
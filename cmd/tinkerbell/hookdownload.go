@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tinkerbell/operator/pkg/hook"
+)
+
+// runHookDownload runs the operator binary as Hook artifact downloader. The operator runs it in a Job next to the nginx
+// proxy which serves the published artifacts.
+func runHookDownload(args []string) error {
+	fs := flag.NewFlagSet(hook.DownloadCommand, flag.ExitOnError)
+	dir := fs.String("dir", "", "The directory the Hook artifacts are published into.")
+	archivesJSON := fs.String("archives", "", "The JSON encoded list of Hook archives and their artifacts to publish.")
+	timeout := fs.Duration("timeout", 30*time.Minute, "The timeout for downloading all the archives.")
+	installTo := fs.String("install-to", "", "Copy the downloader binary to the given path and exit. It is used to run the downloader in a Hook source image.")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *installTo != "" {
+		return installBinary(*installTo)
+	}
+
+	if *dir == "" {
+		return fmt.Errorf("--dir is required")
+	}
+
+	var archives []hook.Archive
+	if err := json.Unmarshal([]byte(*archivesJSON), &archives); err != nil {
+		return fmt.Errorf("failed to parse --archives: %w", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	ctx, cancel = context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	publisher := &hook.Publisher{
+		Dir:    *dir,
+		Client: http.DefaultClient,
+		Logf: func(format string, args ...interface{}) {
+			fmt.Printf(format+"\n", args...)
+		},
+	}
+
+	return publisher.Publish(ctx, archives)
+}
+
+// installBinary copies the running binary to the given path, so that it can be run from another container.
+func installBinary(dst string) error {
+	src, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to determine the downloader binary: %w", err)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy the downloader binary: %w", err)
+	}
+
+	return out.Close()
+}
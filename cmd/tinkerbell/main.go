@@ -1,20 +1,41 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"go.uber.org/zap"
 
+	"github.com/tinkerbell/operator/api/v1alpha1"
+	"github.com/tinkerbell/operator/api/v1alpha2"
 	operatorctrl "github.com/tinkerbell/operator/pkg/controller"
+	"github.com/tinkerbell/operator/pkg/hook"
+	operatorwebhook "github.com/tinkerbell/operator/pkg/webhook"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == hook.DownloadCommand {
+		if err := runHookDownload(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Configure logger
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -30,25 +51,49 @@ func main() {
 		log.Fatalf("failed to create runtime manager: %v", err)
 	}
 
-	if err := operatorctrl.Add(mgr, log, opts.clusterDNS, opts.namespace, opts.workerCount); err != nil {
+	if err := operatorctrl.Add(mgr, log, opts.clusterDNS, opts.clusterDNSService, opts.resolvConf, opts.clusterDomain, opts.namespace, opts.overwriteRegistry, opts.dockerPullConfigJSONFile, opts.hookDownloaderImage, opts.workerCount); err != nil {
 		log.Fatalf("failed to add controller to manager: %v", err)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+	if err := addWebhooks(ctx, mgr, log, opts); err != nil {
+		log.Fatalf("failed to add webhooks to manager: %v", err)
+	}
+
 	log.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		log.Fatalf("Failed to start controller: %v", zap.Error(err))
 	}
 }
 
 func createManager(opts *controllerRunOptions) (manager.Manager, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add kubernetes types to scheme: %w", err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add apiextensions types to scheme: %w", err)
+	}
+	// v1alpha1 is registered for the conversion webhook, the operator itself works with the v1alpha2 hub version.
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add tinkerbell v1alpha1 types to scheme: %w", err)
+	}
+	if err := v1alpha2.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("failed to add tinkerbell types to scheme: %w", err)
+	}
+
 	// Manager options
 	options := manager.Options{
+		Scheme:                  scheme,
 		LeaderElection:          opts.enableLeaderElection,
 		LeaderElectionID:        "tinkerbell-controller",
 		LeaderElectionNamespace: opts.leaderElectionNamespace,
 		HealthProbeBindAddress:  opts.healthProbeAddress,
 		MetricsBindAddress:      opts.metricsAddress,
-		Port:                    9443,
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port:    9443,
+			CertDir: opts.webhookCertDir,
+		}),
 	}
 
 	mgr, err := manager.New(config.GetConfigOrDie(), options)
@@ -65,3 +110,36 @@ func createManager(opts *controllerRunOptions) (manager.Manager, error) {
 	}
 	return mgr, nil
 }
+
+// addWebhooks registers the Stack webhooks and the rotation of their serving certificate. The certificate is issued
+// before the manager starts, as the webhook server can't start without it.
+func addWebhooks(ctx context.Context, mgr manager.Manager, log *zap.SugaredLogger, opts *controllerRunOptions) error {
+	ns, name, ok := strings.Cut(opts.webhookService, "/")
+	if !ok || ns == "" || name == "" {
+		return fmt.Errorf("webhook service %q is not in the namespace/name format", opts.webhookService)
+	}
+
+	// The cache of the manager client isn't started yet.
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	rotator := &operatorwebhook.CertRotator{
+		Client:        c,
+		Log:           log,
+		Service:       types.NamespacedName{Namespace: ns, Name: name},
+		ClusterDomain: opts.clusterDomain,
+		CertDir:       opts.webhookCertDir,
+	}
+
+	if err := rotator.Ensure(ctx); err != nil {
+		return fmt.Errorf("failed to issue webhook certificate: %w", err)
+	}
+
+	if err := mgr.Add(rotator); err != nil {
+		return fmt.Errorf("failed to add webhook certificate rotator: %w", err)
+	}
+
+	return operatorwebhook.Add(mgr)
+}
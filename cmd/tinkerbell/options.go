@@ -9,11 +9,17 @@ type controllerRunOptions struct {
 	kubeconfig              string
 	leaderElectionNamespace string
 	clusterDNS              string
+	clusterDNSService       string
+	resolvConf              string
+	clusterDomain           string
 
 	workerCount              int
 	overwriteRegistry        string
 	dockerPullConfigJSONFile string
 	namespace                string
+	hookDownloaderImage      string
+	webhookService           string
+	webhookCertDir           string
 
 	healthProbeAddress string
 	metricsAddress     string
@@ -28,12 +34,18 @@ func newControllerOptions() *controllerRunOptions {
 	flag.BoolVar(&opts.enableLeaderElection, "enable-leader-election", true, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.StringVar(&opts.leaderElectionNamespace, "leader-election-namespace", "kube-system", "Leader election namespace. In-cluster discovery will be attempted in such case.")
 	flag.IntVar(&opts.workerCount, "worker-count", 1, "Number of workers which process the clusters in parallel.")
-	flag.StringVar(&opts.overwriteRegistry, "overwrite-registry", "", "Registry to use for all images")
+	flag.StringVar(&opts.overwriteRegistry, "overwrite-registry", "", "Registry to use for all images. It takes precedence over the registry set in the Stack.")
 	flag.StringVar(&opts.dockerPullConfigJSONFile, "docker-pull-config-json-file", "", "The file containing the docker auth config.")
-	flag.StringVar(&opts.namespace, "namespace", "tinkerbell", "The namespace where the tinkerbell controller runs in.")
+	flag.StringVar(&opts.hookDownloaderImage, "hook-downloader-image", "tinkerbell/operator:v0.1.0", "The operator image the Hook artifacts are downloaded with. It should match the image of the running operator.")
+	flag.StringVar(&opts.namespace, "namespace", "", "The namespace of the stacks the tinkerbell controller manages. Stacks of all namespaces are managed if empty.")
+	flag.StringVar(&opts.webhookService, "webhook-service", "tinkerbell/tinkerbell-operator-webhook", "The namespace/name of the service the API server reaches the operator webhooks through. The webhook serving certificate is issued for it and stored in its namespace")
+	flag.StringVar(&opts.webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "The directory the webhook serving certificate is written to and served from")
 	flag.StringVar(&opts.healthProbeAddress, "health-probe-address", "127.0.0.1:8085", "The address on which the liveness check on /healthz and readiness check on /readyz will be available")
 	flag.StringVar(&opts.metricsAddress, "metrics-address", "127.0.0.1:8080", "The address on which Prometheus metrics will be available under /metrics")
-	flag.StringVar(&opts.clusterDNS, "cluster-dns", "", "The ip address of of the cluster dns resolver")
+	flag.StringVar(&opts.clusterDNS, "cluster-dns", "", "The ip address of of the cluster dns resolver. It is discovered if not set")
+	flag.StringVar(&opts.clusterDNSService, "cluster-dns-service", "kube-system/kube-dns", "The namespace/name of the cluster dns service the resolver is discovered from. Set it to an empty string to disable the lookup")
+	flag.StringVar(&opts.resolvConf, "resolv-conf", "/etc/resolv.conf", "The resolv.conf the resolver is discovered from if the cluster dns service is not found. Set it to an empty string to disable the lookup")
+	flag.StringVar(&opts.clusterDomain, "cluster-domain", "cluster.local", "The DNS domain of the cluster, used to address the tinkerbell services")
 
 	flag.Parse()
 
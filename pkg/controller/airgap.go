@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// airgapMissing returns the local sources an airgapped stack is missing. It returns nothing for stacks which are not
+// airgapped.
+func (r *Reconciler) airgapMissing(stack *v1alpha2.Stack) []string {
+	if !stack.Spec.Airgapped {
+		return nil
+	}
+
+	var missing []string
+	if r.registry(stack) == "" {
+		missing = append(missing, "a registry mirroring the stack images, set through spec.registry or the operator --overwrite-registry flag")
+	}
+
+	if hookArtifacts := stack.Spec.HookArtifacts; hookArtifacts == nil || (hookArtifacts.Source == nil && hookArtifacts.URL == "") {
+		missing = append(missing, "a local source of the Hook artifacts, set through spec.hookArtifacts.source or spec.hookArtifacts.url")
+	}
+
+	return missing
+}
+
+// ensureAirgapped records in the Airgapped condition whether an airgapped stack has all its local sources configured.
+// It returns an error if sources are missing, so that nothing referencing a public source is deployed.
+func (r *Reconciler) ensureAirgapped(stack *v1alpha2.Stack) error {
+	if !stack.Spec.Airgapped {
+		meta.RemoveStatusCondition(&stack.Status.Conditions, v1alpha2.ConditionAirgapped)
+		return nil
+	}
+
+	missing := r.airgapMissing(stack)
+	if len(missing) > 0 {
+		message := airgapMessage(missing)
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionAirgapped, false, "MissingLocalSources", message)
+
+		return errors.New(message)
+	}
+
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionAirgapped, true, "LocalSourcesConfigured",
+		"all the images and Hook artifacts are loaded from local sources")
+
+	return nil
+}
+
+func airgapMessage(missing []string) string {
+	return fmt.Sprintf("the airgapped stack is missing %s", strings.Join(missing, " and "))
+}
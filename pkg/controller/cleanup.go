@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StackFinalizer is set on every Stack so that the operator can tear down the stack resources before the Stack is gone.
+const StackFinalizer = "tinkerbell.org/stack-cleanup"
+
+// tinkerbellCRDs are the CRDs of the Tinkerbell services which are removed when the Stack CRDDeletionPolicy is Delete.
+var tinkerbellCRDs = []string{
+	"hardware.tinkerbell.org",
+	"templates.tinkerbell.org",
+	"workflows.tinkerbell.org",
+	"workflowdata.tinkerbell.org",
+	"jobs.bmc.tinkerbell.org",
+	"machines.bmc.tinkerbell.org",
+	"tasks.bmc.tinkerbell.org",
+}
+
+// cleanupStep is a single group of objects which must be gone before the next group is deleted.
+type cleanupStep struct {
+	list       client.ObjectList
+	namespaced bool
+}
+
+// cleanupSteps returns the stack objects in the order they are torn down. Workloads go first so that nothing is left
+// running with revoked permissions, and cluster-scoped RBAC goes last as it can't be garbage collected through owner
+// references.
+func cleanupSteps() []cleanupStep {
+	return []cleanupStep{
+		{list: &batchv1.JobList{}, namespaced: true},
+		{list: &appsv1.DeploymentList{}, namespaced: true},
+		{list: &corev1.PersistentVolumeClaimList{}, namespaced: true},
+		{list: &corev1.ServiceList{}, namespaced: true},
+		{list: &corev1.ConfigMapList{}, namespaced: true},
+		{list: &rbacv1.RoleBindingList{}, namespaced: true},
+		{list: &rbacv1.RoleList{}, namespaced: true},
+		{list: &corev1.ServiceAccountList{}, namespaced: true},
+		{list: &rbacv1.ClusterRoleBindingList{}},
+		{list: &rbacv1.ClusterRoleList{}},
+	}
+}
+
+// cleanup deletes the objects of the given stack step by step. It returns true once all objects are gone, otherwise
+// the caller is expected to requeue the stack until the pending deletions are done.
+func (r *Reconciler) cleanup(ctx context.Context, stack *v1alpha2.Stack) (bool, error) {
+	done, err := r.deleteObjects(ctx, stack, util.StackLabels(stack))
+	if err != nil || !done {
+		return done, err
+	}
+
+	if stack.Spec.CRDDeletionPolicy != v1alpha2.CRDDeletionPolicyDelete {
+		return true, nil
+	}
+
+	// The CRDs are shared by all the stacks of the cluster, they are only deleted with the last one.
+	stacks := &v1alpha2.StackList{}
+	if err := r.List(ctx, stacks); err != nil {
+		return false, fmt.Errorf("failed to list stacks: %w", err)
+	}
+
+	for _, other := range stacks.Items {
+		if other.Namespace != stack.Namespace || other.Name != stack.Name {
+			r.log.Infof("keeping the CRDs of stack %s/%s, they are still used by stack %s/%s", stack.Namespace, stack.Name, other.Namespace, other.Name)
+			return true, nil
+		}
+	}
+
+	return r.cleanupCRDs(ctx)
+}
+
+// deleteObjects deletes the stack objects matching the given labels in the order of the cleanup steps. It returns
+// true once no matching object is left.
+func (r *Reconciler) deleteObjects(ctx context.Context, stack *v1alpha2.Stack, labels map[string]string) (bool, error) {
+	for _, step := range cleanupSteps() {
+		opts := []client.ListOption{client.MatchingLabels(labels)}
+		if step.namespaced {
+			opts = append(opts, client.InNamespace(stack.Namespace))
+		}
+
+		if err := r.List(ctx, step.list, opts...); err != nil {
+			return false, fmt.Errorf("failed to list %T: %w", step.list, err)
+		}
+
+		items, err := meta.ExtractList(step.list)
+		if err != nil {
+			return false, fmt.Errorf("failed to extract %T items: %w", step.list, err)
+		}
+
+		if len(items) == 0 {
+			continue
+		}
+
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				return false, fmt.Errorf("unexpected item type %T in %T", item, step.list)
+			}
+
+			if err := r.Delete(ctx, obj, client.PropagationPolicy("Foreground")); err != nil && !kerrors.IsNotFound(err) {
+				return false, fmt.Errorf("failed to delete %T %q: %w", obj, obj.GetName(), err)
+			}
+		}
+
+		r.log.Infof("waiting for %d %T objects of stack %s/%s to be deleted", len(items), step.list, stack.Namespace, stack.Name)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (r *Reconciler) cleanupCRDs(ctx context.Context) (bool, error) {
+	done := true
+	for _, name := range tinkerbellCRDs {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, crd); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+
+			return false, fmt.Errorf("failed to get crd %q: %w", name, err)
+		}
+
+		done = false
+		if crd.DeletionTimestamp != nil {
+			continue
+		}
+
+		if err := r.Delete(ctx, crd); err != nil && !kerrors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to delete crd %q: %w", name, err)
+		}
+	}
+
+	return done, nil
+}
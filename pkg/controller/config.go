@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// config returns the configuration used to render the resources of the given stack.
+func (r *Reconciler) config(ctx context.Context, stack *v1alpha2.Stack) (resources.Config, error) {
+	rel, err := release.Get(stack.Spec.Version)
+	if err != nil {
+		return resources.Config{}, err
+	}
+
+	cfg := resources.Config{
+		Namespace:           stack.Namespace,
+		Release:             rel,
+		Registry:            r.registry(stack),
+		ClusterDomain:       r.clusterDomain,
+		HookDownloaderImage: r.hookDownloaderImage,
+	}
+
+	// The nginx pods download the artifacts of the EmptyDir storage themselves.
+	if tink.HookStorage(stack).Type == v1alpha2.HookStorageTypeEmptyDir {
+		_, archives, err := hook.Archives(stack.Spec.HookArtifacts, rel.Hook)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to resolve hook artifacts: %w", err)
+		}
+		cfg.HookArchives = archives
+	}
+
+	clusterDNS, err := r.resolveClusterDNS(ctx, stack)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ClusterDNS = clusterDNS
+
+	pullSecrets, err := r.ensureImagePullSecrets(ctx, stack)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.ImagePullSecrets = pullSecrets
+
+	trustedProxies, err := r.trustedProxies(ctx)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	publicIP, err := r.publicIP(ctx, stack)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to determine the public address: %w", err)
+	}
+	cfg.PublicIP = publicIP
+
+	tls, err := r.ensureTinkServerTLS(ctx, stack, cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to ensure tink-server TLS: %w", err)
+	}
+	cfg.TinkServerTLS = tls
+
+	return cfg, nil
+}
+
+// trustedProxies returns the pod CIDRs of all the cluster nodes, the nginx proxy forwards requests to the services from
+// these ranges.
+func (r *Reconciler) trustedProxies(ctx context.Context) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	cidrs := sets.New[string]()
+	for _, node := range nodes.Items {
+		cidrs.Insert(node.Spec.PodCIDRs...)
+		if node.Spec.PodCIDR != "" {
+			cidrs.Insert(node.Spec.PodCIDR)
+		}
+	}
+
+	return sets.List(cidrs), nil
+}
+
+// registry returns the registry all the stack images are pulled from. The operator --overwrite-registry flag takes
+// precedence over the Stack registry, so that cluster admins can enforce a registry for every stack.
+func (r *Reconciler) registry(stack *v1alpha2.Stack) string {
+	if r.overwriteRegistry != "" {
+		return r.overwriteRegistry
+	}
+
+	if stack.Spec.Registry != nil {
+		return *stack.Spec.Registry
+	}
+
+	return ""
+}
+
+// ensureImagePullSecrets creates the image pull secret from the operator docker config file, if one is configured, and
+// returns it along with the image pull secrets listed in the Stack.
+func (r *Reconciler) ensureImagePullSecrets(ctx context.Context, stack *v1alpha2.Stack) ([]corev1.LocalObjectReference, error) {
+	if r.dockerPullConfigJSONFile != "" {
+		dockerConfigJSON, err := os.ReadFile(r.dockerPullConfigJSONFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read docker pull config file: %w", err)
+		}
+
+		if !json.Valid(dockerConfigJSON) {
+			return nil, fmt.Errorf("docker pull config file %q is not valid json", r.dockerPullConfigJSONFile)
+		}
+
+		if err := resources.CreateImagePullSecret(ctx, r.Client, stack, dockerConfigJSON, stack.Namespace); err != nil {
+			return nil, fmt.Errorf("failed to create image pull secret: %w", err)
+		}
+	}
+
+	return r.imagePullSecrets(stack), nil
+}
+
+// imagePullSecrets returns the image pull secrets of the stack workloads without creating them.
+func (r *Reconciler) imagePullSecrets(stack *v1alpha2.Stack) []corev1.LocalObjectReference {
+	var pullSecrets []corev1.LocalObjectReference
+
+	if r.dockerPullConfigJSONFile != "" {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: resources.ImagePullSecretName})
+	}
+
+	for _, name := range stack.Spec.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, corev1.LocalObjectReference{Name: name})
+	}
+
+	return pullSecrets
+}
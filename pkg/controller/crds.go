@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ensureTinkerbellCRDs installs the CRDs of the Stack version before any component is deployed, and records their state
+// in the stack status. CRDs which would stop serving a version existing objects are stored in are not applied, as the
+// API server could no longer read those objects.
+func (r *Reconciler) ensureTinkerbellCRDs(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config) error {
+	statuses := make([]v1alpha2.CRDStatus, 0, len(cfg.Release.CRDs))
+	var refused, pending []string
+
+	for i := range cfg.Release.CRDs {
+		crd := cfg.Release.CRDs[i].DeepCopy()
+
+		existing := &apiextensionsv1.CustomResourceDefinition{}
+		if err := r.Get(ctx, types.NamespacedName{Name: crd.Name}, existing); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to get crd %q: %v", crd.Name, err)
+			}
+			existing = nil
+		}
+
+		if existing != nil {
+			if dropped := droppedStoredVersions(existing, crd); len(dropped) > 0 {
+				refused = append(refused, fmt.Sprintf("%s (stored versions %s)", crd.Name, strings.Join(dropped, ", ")))
+				statuses = append(statuses, crdStatus(existing))
+				continue
+			}
+		}
+
+		if err := util.Apply(ctx, r.Client, crd); err != nil {
+			return fmt.Errorf("failed to apply crd %q: %v", crd.Name, err)
+		}
+
+		status := crdStatus(crd)
+		if !status.Established {
+			pending = append(pending, crd.Name)
+		}
+		statuses = append(statuses, status)
+	}
+
+	stack.Status.CRDs = statuses
+
+	switch {
+	case len(refused) > 0:
+		message := fmt.Sprintf("refusing to downgrade the CRDs of version %s, they don't serve versions existing objects are stored in: %s",
+			cfg.Release.Version, strings.Join(refused, "; "))
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionCRDsEstablished, false, "DowngradeRefused", message)
+
+		return errors.New(message)
+	case len(pending) > 0:
+		message := fmt.Sprintf("waiting for the CRDs to be established: %s", strings.Join(pending, ", "))
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionCRDsEstablished, false, "Pending", message)
+
+		return pendingf("%s", message)
+	}
+
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionCRDsEstablished, true, "Established",
+		fmt.Sprintf("the CRDs of version %s are established", cfg.Release.Version))
+
+	return nil
+}
+
+// droppedStoredVersions returns the versions existing objects of the CRD are stored in which the new CRD doesn't serve
+// anymore. The version validation of the Stack webhook refuses the same downgrades.
+func droppedStoredVersions(existing, crd *apiextensionsv1.CustomResourceDefinition) []string {
+	served := sets.New[string]()
+	for _, version := range crd.Spec.Versions {
+		if version.Served {
+			served.Insert(version.Name)
+		}
+	}
+
+	var dropped []string
+	for _, stored := range existing.Status.StoredVersions {
+		if !served.Has(stored) {
+			dropped = append(dropped, stored)
+		}
+	}
+
+	return dropped
+}
+
+// crdStatus returns the status of the given CRD as observed by the API server.
+func crdStatus(crd *apiextensionsv1.CustomResourceDefinition) v1alpha2.CRDStatus {
+	status := v1alpha2.CRDStatus{
+		Name:           crd.Name,
+		StoredVersions: crd.Status.StoredVersions,
+	}
+
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			status.StorageVersion = version.Name
+		}
+	}
+
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+			status.Established = true
+		}
+	}
+
+	return status
+}
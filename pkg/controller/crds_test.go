@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testCRD(versions ...apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "workflows.tinkerbell.org"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group:    "tinkerbell.org",
+			Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: "Workflow", Plural: "workflows"},
+			Scope:    apiextensionsv1.NamespaceScoped,
+			Versions: versions,
+		},
+	}
+}
+
+func crdVersion(name string, served, storage bool) apiextensionsv1.CustomResourceDefinitionVersion {
+	return apiextensionsv1.CustomResourceDefinitionVersion{Name: name, Served: served, Storage: storage}
+}
+
+func establishedCRD(crd *apiextensionsv1.CustomResourceDefinition, storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd = crd.DeepCopy()
+	crd.Status.StoredVersions = storedVersions
+	crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{{
+		Type:   apiextensionsv1.Established,
+		Status: apiextensionsv1.ConditionTrue,
+	}}
+
+	return crd
+}
+
+func TestDroppedStoredVersions(t *testing.T) {
+	tests := map[string]struct {
+		storedVersions []string
+		crd            *apiextensionsv1.CustomResourceDefinition
+		expected       []string
+	}{
+		"stored version served": {
+			storedVersions: []string{"v1alpha1"},
+			crd:            testCRD(crdVersion("v1alpha1", true, true)),
+		},
+		"stored versions served by a newer CRD": {
+			storedVersions: []string{"v1alpha1", "v1alpha2"},
+			crd:            testCRD(crdVersion("v1alpha1", true, false), crdVersion("v1alpha2", true, true)),
+		},
+		"stored version removed": {
+			storedVersions: []string{"v1alpha1", "v1alpha2"},
+			crd:            testCRD(crdVersion("v1alpha1", true, true)),
+			expected:       []string{"v1alpha2"},
+		},
+		"stored version no longer served": {
+			storedVersions: []string{"v1alpha1", "v1alpha2"},
+			crd:            testCRD(crdVersion("v1alpha1", false, false), crdVersion("v1alpha2", true, true)),
+			expected:       []string{"v1alpha1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			existing := establishedCRD(test.crd, test.storedVersions...)
+
+			if dropped := droppedStoredVersions(existing, test.crd); !apiequality.Semantic.DeepEqual(dropped, test.expected) {
+				t.Errorf("expected the dropped versions %v, got %v", test.expected, dropped)
+			}
+		})
+	}
+}
+
+func TestEnsureTinkerbellCRDs(t *testing.T) {
+	crd := testCRD(crdVersion("v1alpha1", true, true))
+
+	tests := map[string]struct {
+		existing        []client.Object
+		crd             *apiextensionsv1.CustomResourceDefinition
+		expectedReason  string
+		expectedPending bool
+		expectErr       bool
+	}{
+		"new CRD which isn't established yet": {
+			crd:             crd,
+			expectedReason:  "Pending",
+			expectedPending: true,
+			expectErr:       true,
+		},
+		"established CRD": {
+			existing:       []client.Object{establishedCRD(crd, "v1alpha1")},
+			crd:            crd,
+			expectedReason: "Established",
+		},
+		"downgrade dropping a stored version": {
+			existing:       []client.Object{establishedCRD(testCRD(crdVersion("v1alpha1", true, false), crdVersion("v1alpha2", true, true)), "v1alpha1", "v1alpha2")},
+			crd:            crd,
+			expectedReason: "DowngradeRefused",
+			expectErr:      true,
+		},
+		"downgrade to a CRD not serving a stored version": {
+			existing:       []client.Object{establishedCRD(crd, "v1alpha1")},
+			crd:            testCRD(crdVersion("v1alpha1", false, false), crdVersion("v1alpha2", true, true)),
+			expectedReason: "DowngradeRefused",
+			expectErr:      true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newTestReconciler(t, test.existing...)
+			stack := testStack()
+			cfg := resources.Config{Release: release.Release{Version: "v0.8.0", CRDs: []apiextensionsv1.CustomResourceDefinition{*test.crd}}}
+
+			err := r.ensureTinkerbellCRDs(context.Background(), stack, cfg)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %t, got %v", test.expectErr, err)
+			}
+
+			var pending *pendingError
+			if errors.As(err, &pending) != test.expectedPending {
+				t.Errorf("expected a pending error %t, got %v", test.expectedPending, err)
+			}
+
+			cond := meta.FindStatusCondition(stack.Status.Conditions, string(v1alpha2.ConditionCRDsEstablished))
+			if cond == nil || cond.Reason != test.expectedReason {
+				t.Fatalf("expected a CRDsEstablished condition with reason %q, got %+v", test.expectedReason, cond)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// resolveClusterDNS returns the IP address of the cluster DNS resolver the nginx proxy uses. The Stack setting takes
+// precedence over the --cluster-dns flag. If neither is set, the resolver is discovered from the cluster DNS Service
+// and, as a fallback, from the nameserver in the resolv.conf of the operator pod.
+func (r *Reconciler) resolveClusterDNS(ctx context.Context, stack *v1alpha2.Stack) (string, error) {
+	if stack.Spec.DNSResolverIP != nil && *stack.Spec.DNSResolverIP != "" {
+		return *stack.Spec.DNSResolverIP, nil
+	}
+
+	if r.clusterDNS != "" {
+		return r.clusterDNS, nil
+	}
+
+	if r.clusterDNSService.Name != "" {
+		ip, err := r.clusterDNSServiceIP(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		if ip != "" {
+			return ip, nil
+		}
+	}
+
+	if r.resolvConf != "" {
+		ip, err := resolvConfNameserver(r.resolvConf)
+		if err != nil {
+			return "", err
+		}
+
+		if ip != "" {
+			return ip, nil
+		}
+	}
+
+	return "", errors.New("failed to discover the cluster DNS resolver, set it with the --cluster-dns flag or the Stack dnsResolverIP")
+}
+
+func (r *Reconciler) clusterDNSServiceIP(ctx context.Context) (string, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, r.clusterDNSService, service); err != nil {
+		if kerrors.IsNotFound(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to get cluster DNS service %q: %w", r.clusterDNSService, err)
+	}
+
+	if service.Spec.ClusterIP == corev1.ClusterIPNone {
+		return "", nil
+	}
+
+	return service.Spec.ClusterIP, nil
+}
+
+// resolvConfNameserver returns the first nameserver of the given resolv.conf file.
+func resolvConfNameserver(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1], nil
+		}
+	}
+
+	return "", scanner.Err()
+}
+
+// isClusterDNSService returns whether the object is the cluster DNS Service the resolver is discovered from.
+func (r *Reconciler) isClusterDNSService(obj ctrlruntimeclient.Object) bool {
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()} == r.clusterDNSService
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// fileWatcher enqueues all the stacks of a namespace whenever the watched file changes.
+type fileWatcher struct {
+	client    client.Client
+	log       *zap.SugaredLogger
+	file      string
+	namespace string
+	events    chan event.GenericEvent
+}
+
+var _ manager.Runnable = &fileWatcher{}
+
+// Start watches the directory of the file rather than the file itself, as files mounted from secrets are replaced
+// through a symlink swap which isn't reported for the file path.
+func (w *fileWatcher) Start(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(w.file)); err != nil {
+		return fmt.Errorf("failed to watch %q: %w", w.file, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.log.Errorf("error watching %q: %v", w.file, err)
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.enqueueStacks(ctx)
+		}
+	}
+}
+
+func (w *fileWatcher) enqueueStacks(ctx context.Context) {
+	stacks := &v1alpha2.StackList{}
+	if err := w.client.List(ctx, stacks, client.InNamespace(w.namespace)); err != nil {
+		w.log.Errorf("failed to list stacks after %q changed: %v", w.file, err)
+		return
+	}
+
+	for i := range stacks.Items {
+		w.events <- event.GenericEvent{Object: &stacks.Items[i]}
+	}
+}
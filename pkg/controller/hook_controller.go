@@ -0,0 +1,457 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrlruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	HookControllerName = "HookController"
+
+	// hookDownloadFailedReason is the reason of the Ready condition of Hook artifacts whose download failed.
+	hookDownloadFailedReason = "DownloadFailed"
+	// hookDownloadRetryBaseDelay is the delay after which a failed download Job is retried for the first time.
+	hookDownloadRetryBaseDelay = time.Minute
+	// hookDownloadMaxRetryDelay is the maximum delay after which a failed download Job is retried.
+	hookDownloadMaxRetryDelay = 30 * time.Minute
+)
+
+// addHookController adds the controller publishing the Hook artifacts of the stacks. The artifacts are published by a
+// Job into the storage the nginx proxy serves them from, unless every nginx pod downloads them itself.
+func addHookController(mgr manager.Manager, stackReconciler *Reconciler, workerCount int) error {
+	reconciler := &HookReconciler{Reconciler: stackReconciler}
+
+	c, err := controller.New(HookControllerName, mgr, controller.Options{Reconciler: reconciler, MaxConcurrentReconciles: workerCount})
+	if err != nil {
+		return err
+	}
+
+	namespace := stackReconciler.namespace
+	if err := c.Watch(source.Kind(mgr.GetCache(), &v1alpha2.Stack{}), &handler.EnqueueRequestForObject{}, util.ByNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to create watch for %T: %w", &v1alpha2.Stack{}, err)
+	}
+
+	ownerHandler := handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &v1alpha2.Stack{}, handler.OnlyControllerOwner())
+	if err := c.Watch(source.Kind(mgr.GetCache(), &batchv1.Job{}), ownerHandler, util.ByNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to create watch for %T: %w", &batchv1.Job{}, err)
+	}
+
+	// Node local storage is published on the node of the nginx proxy, so the artifacts are published again whenever it
+	// moves. The nginx pods also report the downloads of the EmptyDir storage.
+	nginxHandler := handler.EnqueueRequestsFromMapFunc(reconciler.enqueueNamespaceStacks)
+	if err := c.Watch(source.Kind(mgr.GetCache(), &corev1.Pod{}), nginxHandler, util.Factory(reconciler.isNginxPod)); err != nil {
+		return fmt.Errorf("failed to create watch for nginx pods: %w", err)
+	}
+
+	return nil
+}
+
+// HookReconciler publishes the Hook artifacts of a stack and reports their state in the stack status. It shares the
+// configuration of the stack reconciler.
+type HookReconciler struct {
+	*Reconciler
+}
+
+func (r *HookReconciler) Reconcile(ctx context.Context, req ctrlruntime.Request) (reconcile.Result, error) {
+	stack := &v1alpha2.Stack{}
+	if err := r.Get(ctx, req.NamespacedName, stack); err != nil {
+		if kerrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{}, fmt.Errorf("failed to get stack %q: %w", req.NamespacedName, err)
+	}
+
+	// The download jobs are torn down by the stack cleanup.
+	if !stack.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, nil
+	}
+
+	original := stack.DeepCopy()
+	if stack.Status.HookArtifacts == nil {
+		stack.Status.HookArtifacts = &v1alpha2.HookArtifactsStatus{}
+	}
+
+	result, reconcileErr := r.reconcileHook(ctx, stack, stack.Status.HookArtifacts)
+	if reconcileErr != nil {
+		r.log.Errorf("failed to publish hook artifacts of %q due to: %v", req.Name, reconcileErr)
+		setCondition(&stack.Status.HookArtifacts.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "ReconcileFailed", reconcileErr.Error())
+	}
+
+	if err := r.Status().Patch(ctx, stack, client.MergeFrom(original)); err != nil {
+		r.log.Errorf("failed to update hook artifacts status of %q due to: %v", req.Name, err)
+		if reconcileErr == nil {
+			return reconcile.Result{}, fmt.Errorf("failed to patch stack status: %w", err)
+		}
+	}
+
+	return result, reconcileErr
+}
+
+func (r *HookReconciler) reconcileHook(ctx context.Context, stack *v1alpha2.Stack, status *v1alpha2.HookArtifactsStatus) (reconcile.Result, error) {
+	rel, err := release.Get(stack.Spec.Version)
+	if err != nil {
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "InvalidSpec", err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	version, archives, err := hook.Archives(stack.Spec.HookArtifacts, rel.Hook)
+	if err != nil {
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "InvalidSpec", err.Error())
+		return reconcile.Result{}, nil
+	}
+
+	// The artifacts of an airgapped stack are only published once they don't come from a public source.
+	if missing := r.airgapMissing(stack); len(missing) > 0 {
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "MissingLocalSources", airgapMessage(missing))
+		return reconcile.Result{}, nil
+	}
+
+	status.Version = version
+	status.Artifacts = hook.ArtifactNames(archives)
+	status.Node = ""
+
+	storage := tink.HookStorage(stack)
+	if storage.Type == v1alpha2.HookStorageTypeEmptyDir {
+		ready, reason, message, err := r.nginxDownloadStatus(ctx, stack.Namespace, version)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, ready, reason, message)
+
+		return reconcile.Result{}, r.deleteStaleHookDownloadJobs(ctx, stack, "")
+	}
+
+	var node string
+	if tink.HookStorageNodeLocal(storage) {
+		node, err = r.nginxNodeName(ctx, stack.Namespace)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		if node == "" {
+			setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "WaitingForNginx", "the nginx proxy is not scheduled yet")
+			return reconcile.Result{}, nil
+		}
+		status.Node = node
+	}
+
+	cfg := resources.Config{
+		Namespace:        stack.Namespace,
+		Registry:         r.registry(stack),
+		ImagePullSecrets: r.imagePullSecrets(stack),
+	}
+
+	name, err := hookDownloadJobName(cfg, stack.Spec.HookArtifacts, storage, archives, node, r.hookDownloaderImage)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	job, attempt, err := r.currentHookDownloadJob(ctx, stack, name)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	current := name
+	var result reconcile.Result
+	if job == nil {
+		if err := tink.CreateHookDownloadJob(ctx, r.Client, stack, cfg, name, node, r.hookDownloaderImage, archives); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to create hook download job: %w", err)
+		}
+
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, false, "Downloading", publishingMessage(version, node))
+	} else {
+		current = job.Name
+
+		ready, reason, message, err := r.hookDownloadJobStatus(ctx, job, version)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+
+		// The Job name only changes with the spec, a failed Job is replaced by a new attempt once the retry delay passed,
+		// so that transient failures recover without any change of the stack.
+		if reason == hookDownloadFailedReason {
+			retryAt := hookDownloadFailedAt(job).Add(hookDownloadRetryDelay(attempt))
+			if wait := time.Until(retryAt); wait > 0 {
+				message = fmt.Sprintf("%s, the download is retried at %s", message, retryAt.UTC().Format(time.RFC3339))
+				result.RequeueAfter = wait
+			} else {
+				current = hookDownloadAttemptName(name, attempt+1)
+				if err := tink.CreateHookDownloadJob(ctx, r.Client, stack, cfg, current, node, r.hookDownloaderImage, archives); err != nil {
+					return reconcile.Result{}, fmt.Errorf("failed to create hook download job: %w", err)
+				}
+
+				ready, reason, message = false, "Downloading", publishingMessage(version, node)
+			}
+		}
+
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionReady, ready, reason, message)
+	}
+
+	return result, r.deleteStaleHookDownloadJobs(ctx, stack, current)
+}
+
+// currentHookDownloadJob returns the latest attempt of the download Job with the given name and its attempt number. It
+// returns a nil Job if no attempt exists yet.
+func (r *HookReconciler) currentHookDownloadJob(ctx context.Context, stack *v1alpha2.Stack, name string) (*batchv1.Job, int, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(stack.Namespace), client.MatchingLabels(util.ComponentLabels(stack, tink.HookComponentName))); err != nil {
+		return nil, 0, fmt.Errorf("failed to list hook download jobs: %w", err)
+	}
+
+	var current *batchv1.Job
+	var currentAttempt int
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !job.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		attempt := 0
+		if job.Name != name {
+			suffix, ok := strings.CutPrefix(job.Name, name+"-")
+			if !ok {
+				continue
+			}
+
+			n, err := strconv.Atoi(suffix)
+			if err != nil {
+				continue
+			}
+			attempt = n
+		}
+
+		if current == nil || attempt > currentAttempt {
+			current, currentAttempt = job, attempt
+		}
+	}
+
+	return current, currentAttempt, nil
+}
+
+// hookDownloadAttemptName returns the name of the given attempt of the download Job with the given name.
+func hookDownloadAttemptName(name string, attempt int) string {
+	if attempt == 0 {
+		return name
+	}
+
+	return fmt.Sprintf("%s-%d", name, attempt)
+}
+
+// hookDownloadRetryDelay returns the delay after which the given failed attempt of a download Job is retried. The
+// delay doubles with every attempt, up to hookDownloadMaxRetryDelay.
+func hookDownloadRetryDelay(attempt int) time.Duration {
+	delay := hookDownloadRetryBaseDelay
+	for i := 0; i < attempt && delay < hookDownloadMaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > hookDownloadMaxRetryDelay {
+		return hookDownloadMaxRetryDelay
+	}
+
+	return delay
+}
+
+// hookDownloadFailedAt returns when the given Job failed.
+func hookDownloadFailedAt(job *batchv1.Job) time.Time {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return cond.LastTransitionTime.Time
+		}
+	}
+
+	return job.CreationTimestamp.Time
+}
+
+// hookDownloadJobName returns the name of the Job publishing the given archives. The Job spec is immutable, so every
+// change of the archives, their source, the storage, the node, the downloader image or the download settings results in
+// a new Job.
+func hookDownloadJobName(cfg resources.Config, spec *v1alpha2.HookArtifacts, storage v1alpha2.HookStorage, archives []hook.Archive, node, image string) (string, error) {
+	archivesJSON, err := json.Marshal(archives)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hook archives: %w", err)
+	}
+
+	var source *v1alpha2.HookArtifactsSource
+	var download *v1alpha2.ComponentDeployment
+	if spec != nil {
+		source, download = spec.Source, spec.Download
+	}
+
+	sourceJSON, err := json.Marshal(source)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hook source: %w", err)
+	}
+
+	storageJSON, err := json.Marshal(storage)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hook storage: %w", err)
+	}
+
+	downloadJSON, err := json.Marshal(download)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal hook download settings: %w", err)
+	}
+
+	pullSecrets := make([]string, 0, len(cfg.ImagePullSecrets))
+	for _, secret := range cfg.ImagePullSecrets {
+		pullSecrets = append(pullSecrets, secret.Name)
+	}
+
+	sum := util.Checksum(archivesJSON, sourceJSON, storageJSON, []byte(node), []byte(image), []byte(cfg.Registry), []byte(strings.Join(pullSecrets, ",")), downloadJSON)
+
+	return tink.HookDownloadJobPrefix + sum[:10], nil
+}
+
+// hookDownloadJobStatus returns the Ready condition of the artifacts published by the given Job.
+func (r *HookReconciler) hookDownloadJobStatus(ctx context.Context, job *batchv1.Job, version string) (bool, string, string, error) {
+	node := job.Spec.Template.Spec.NodeSelector[corev1.LabelHostname]
+
+	if job.Status.Succeeded > 0 {
+		if node == "" {
+			return true, "Published", fmt.Sprintf("hook %s artifacts are published", version), nil
+		}
+
+		return true, "Published", fmt.Sprintf("hook %s artifacts are published on node %s", version, node), nil
+	}
+
+	for _, cond := range job.Status.Conditions {
+		if cond.Type != batchv1.JobFailed || cond.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		message, err := r.hookDownloadFailure(ctx, job)
+		if err != nil {
+			return false, "", "", err
+		}
+		if message == "" {
+			message = cond.Message
+		}
+
+		return false, hookDownloadFailedReason, message, nil
+	}
+
+	return false, "Downloading", publishingMessage(version, node), nil
+}
+
+// nginxDownloadStatus returns the Ready condition of the artifacts the nginx pods download into their EmptyDir volume.
+func (r *HookReconciler) nginxDownloadStatus(ctx context.Context, namespace, version string) (bool, string, string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace), nginxPodLabels); err != nil {
+		return false, "", "", fmt.Errorf("failed to list nginx pods: %w", err)
+	}
+
+	if message := lastTerminationFailure(pods.Items, true); message != "" {
+		return false, "DownloadFailed", message, nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: tink.NginxDeploymentName}, deployment); err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, "WaitingForNginx", "the nginx proxy is not deployed yet", nil
+		}
+
+		return false, "", "", fmt.Errorf("failed to get nginx deployment: %w", err)
+	}
+
+	available, _, _ := deploymentAvailable(deployment)
+	progressing, _, _ := deploymentProgressing(deployment)
+	if !available || progressing {
+		return false, "Downloading", fmt.Sprintf("the nginx pods are downloading the hook %s artifacts", version), nil
+	}
+
+	return true, "Published", fmt.Sprintf("hook %s artifacts are downloaded by every nginx pod", version), nil
+}
+
+func publishingMessage(version, node string) string {
+	if node == "" {
+		return fmt.Sprintf("publishing hook %s artifacts", version)
+	}
+
+	return fmt.Sprintf("publishing hook %s artifacts on node %s", version, node)
+}
+
+// hookDownloadFailure returns the termination message of the last failed downloader pod of the given Job.
+func (r *HookReconciler) hookDownloadFailure(ctx context.Context, job *batchv1.Job) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", fmt.Errorf("failed to list pods of job %q: %w", job.Name, err)
+	}
+
+	return lastTerminationFailure(pods.Items, false), nil
+}
+
+// lastTerminationFailure returns the termination message of the last container of the given pods which failed. It
+// only looks at the init containers if initContainers is set.
+func lastTerminationFailure(pods []corev1.Pod, initContainers bool) string {
+	var message string
+	var finishedAt int64
+	for _, pod := range pods {
+		statuses := pod.Status.ContainerStatuses
+		if initContainers {
+			statuses = pod.Status.InitContainerStatuses
+		}
+
+		for _, status := range statuses {
+			for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated == nil || terminated.ExitCode == 0 {
+					continue
+				}
+
+				if at := terminated.FinishedAt.Unix(); message == "" || at >= finishedAt {
+					message, finishedAt = strings.TrimSpace(terminated.Message), at
+				}
+			}
+		}
+	}
+
+	return message
+}
+
+// deleteStaleHookDownloadJobs deletes the download jobs of the stack except the current one.
+func (r *HookReconciler) deleteStaleHookDownloadJobs(ctx context.Context, stack *v1alpha2.Stack, current string) error {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(stack.Namespace), client.MatchingLabels(util.ComponentLabels(stack, tink.HookComponentName))); err != nil {
+		return fmt.Errorf("failed to list hook download jobs: %w", err)
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Name == current || !job.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if err := r.Delete(ctx, job, client.PropagationPolicy("Background")); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete job %q: %w", job.Name, err)
+		}
+	}
+
+	return nil
+}
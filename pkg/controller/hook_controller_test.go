@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testStack() *v1alpha2.Stack {
+	return &v1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "tinkerbell", Namespace: testNamespace, UID: "stack-uid"},
+		Spec:       v1alpha2.StackSpec{Version: "v0.8.0"},
+	}
+}
+
+func hookDownloadJob(stack *v1alpha2.Stack, name string) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: stack.Namespace,
+			Labels:    util.ComponentLabels(stack, tink.HookComponentName),
+		},
+	}
+}
+
+func TestHookDownloadRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		0:   time.Minute,
+		1:   2 * time.Minute,
+		2:   4 * time.Minute,
+		4:   16 * time.Minute,
+		5:   hookDownloadMaxRetryDelay,
+		100: hookDownloadMaxRetryDelay,
+	}
+
+	for attempt, expected := range tests {
+		if delay := hookDownloadRetryDelay(attempt); delay != expected {
+			t.Errorf("expected the delay %v for attempt %d, got %v", expected, attempt, delay)
+		}
+	}
+}
+
+func TestCurrentHookDownloadJob(t *testing.T) {
+	stack := testStack()
+	name := tink.HookDownloadJobPrefix + "0123456789"
+
+	deleted := hookDownloadJob(stack, name+"-3")
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted.Finalizers = []string{"batch.kubernetes.io/job-tracking"}
+
+	otherStack := hookDownloadJob(stack, name+"-4")
+	otherStack.Labels = util.ComponentLabels(&v1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "other"}}, tink.HookComponentName)
+
+	tests := map[string]struct {
+		jobs            []client.Object
+		expected        string
+		expectedAttempt int
+	}{
+		"no jobs": {},
+		"first attempt": {
+			jobs:     []client.Object{hookDownloadJob(stack, name)},
+			expected: name,
+		},
+		"latest attempt": {
+			jobs: []client.Object{
+				hookDownloadJob(stack, name),
+				hookDownloadJob(stack, name+"-2"),
+				hookDownloadJob(stack, name+"-1"),
+			},
+			expected:        name + "-2",
+			expectedAttempt: 2,
+		},
+		"jobs of other archives": {
+			jobs: []client.Object{
+				hookDownloadJob(stack, name+"-1"),
+				hookDownloadJob(stack, tink.HookDownloadJobPrefix+"abcdefghij"),
+				hookDownloadJob(stack, name+"-x"),
+				hookDownloadJob(stack, name+"0-5"),
+			},
+			expected:        name + "-1",
+			expectedAttempt: 1,
+		},
+		"jobs being deleted": {
+			jobs:     []client.Object{hookDownloadJob(stack, name), deleted},
+			expected: name,
+		},
+		"jobs of other stacks": {
+			jobs:     []client.Object{hookDownloadJob(stack, name), otherStack},
+			expected: name,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			r := &HookReconciler{Reconciler: newTestReconciler(t, test.jobs...)}
+
+			job, attempt, err := r.currentHookDownloadJob(context.Background(), stack, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var jobName string
+			if job != nil {
+				jobName = job.Name
+			}
+
+			if jobName != test.expected || attempt != test.expectedAttempt {
+				t.Fatalf("expected job %q at attempt %d, got %q at attempt %d", test.expected, test.expectedAttempt, jobName, attempt)
+			}
+		})
+	}
+}
+
+func TestReconcileHookRetriesFailedDownloads(t *testing.T) {
+	ctx := context.Background()
+	stack := testStack()
+	r := &HookReconciler{Reconciler: newTestReconciler(t, stack, nginxPod("nginx-a", "node-a", time.Now(), true))}
+
+	reconcileHook := func(expectedReason string) (*v1alpha2.HookArtifactsStatus, time.Duration) {
+		t.Helper()
+
+		status := &v1alpha2.HookArtifactsStatus{}
+		result, err := r.reconcileHook(ctx, stack, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(status.Conditions) != 1 || status.Conditions[0].Reason != expectedReason {
+			t.Fatalf("expected a Ready condition with reason %q, got %+v", expectedReason, status.Conditions)
+		}
+
+		return status, result.RequeueAfter
+	}
+
+	jobNames := func() []string {
+		t.Helper()
+
+		jobs := &batchv1.JobList{}
+		if err := r.List(ctx, jobs, client.InNamespace(testNamespace)); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, job := range jobs.Items {
+			names = append(names, job.Name)
+		}
+
+		return names
+	}
+
+	updateJobStatus := func(name string, update func(*batchv1.JobStatus)) {
+		t.Helper()
+
+		job := &batchv1.Job{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, job); err != nil {
+			t.Fatal(err)
+		}
+
+		update(&job.Status)
+		if err := r.Status().Update(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	failJob := func(name string, at time.Time) {
+		t.Helper()
+
+		updateJobStatus(name, func(status *batchv1.JobStatus) {
+			status.Conditions = []batchv1.JobCondition{{
+				Type:               batchv1.JobFailed,
+				Status:             corev1.ConditionTrue,
+				Message:            "Job has reached the specified backoff limit",
+				LastTransitionTime: metav1.NewTime(at),
+			}}
+		})
+	}
+
+	reconcileHook("Downloading")
+	names := jobNames()
+	if len(names) != 1 {
+		t.Fatalf("expected a single download job, got %v", names)
+	}
+	name := names[0]
+
+	// A job which just failed is kept until the retry delay passed.
+	failJob(name, time.Now())
+	status, requeueAfter := reconcileHook(hookDownloadFailedReason)
+	if requeueAfter <= 0 || requeueAfter > hookDownloadRetryBaseDelay {
+		t.Errorf("expected a requeue within %v, got %v", hookDownloadRetryBaseDelay, requeueAfter)
+	}
+	if message := status.Conditions[0].Message; !strings.Contains(message, "the download is retried at") {
+		t.Errorf("expected the condition message to report the retry, got %q", message)
+	}
+	if names := jobNames(); len(names) != 1 || names[0] != name {
+		t.Fatalf("expected the failed job %q to be kept, got %v", name, names)
+	}
+
+	// The failed job is replaced by a new attempt once the retry delay passed.
+	failJob(name, time.Now().Add(-2*time.Minute))
+	reconcileHook("Downloading")
+	if names := jobNames(); len(names) != 1 || names[0] != name+"-1" {
+		t.Fatalf("expected the job %q to be replaced by %q, got %v", name, name+"-1", names)
+	}
+
+	// The retry delay doubles with every attempt.
+	failJob(name+"-1", time.Now().Add(-90*time.Second))
+	_, requeueAfter = reconcileHook(hookDownloadFailedReason)
+	if requeueAfter <= 0 || requeueAfter > 30*time.Second {
+		t.Errorf("expected a requeue within %v, got %v", 30*time.Second, requeueAfter)
+	}
+
+	updateJobStatus(name+"-1", func(status *batchv1.JobStatus) {
+		status.Conditions = nil
+		status.Succeeded = 1
+	})
+	if _, requeueAfter = reconcileHook("Published"); requeueAfter != 0 {
+		t.Errorf("expected no requeue, got %v", requeueAfter)
+	}
+}
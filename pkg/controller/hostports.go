@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ensureHostPorts records in the HostPortsAvailable condition whether the host ports of the Smee of the stack are free.
+// Smee runs on the host network of any node, so the ports of the Smee of two stacks can't overlap. The older stack
+// keeps the ports, the Smee of the newer one isn't deployed and an error is returned until the conflict is resolved.
+func (r *Reconciler) ensureHostPorts(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Smee == nil {
+		meta.RemoveStatusCondition(&stack.Status.Conditions, v1alpha2.ConditionHostPortsAvailable)
+		return nil
+	}
+
+	// Stacks of all namespaces are listed, the host ports are shared by the whole cluster.
+	stacks := &v1alpha2.StackList{}
+	if err := r.List(ctx, stacks); err != nil {
+		return fmt.Errorf("failed to list stacks: %v", err)
+	}
+
+	ports := smeeHostPorts(stack.Spec.Services.Smee)
+
+	var conflicts []string
+	for i := range stacks.Items {
+		other := &stacks.Items[i]
+		if other.Spec.Services.Smee == nil || !other.DeletionTimestamp.IsZero() || !olderStack(other, stack) {
+			continue
+		}
+
+		if shared := sets.List(ports.Intersection(smeeHostPorts(other.Spec.Services.Smee))); len(shared) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s by stack %s/%s", strings.Join(shared, ", "), other.Namespace, other.Name))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		message := fmt.Sprintf("smee is not deployed, its host ports are already used: %s", strings.Join(conflicts, "; "))
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionHostPortsAvailable, false, "Conflict", message)
+
+		return errors.New(message)
+	}
+
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionHostPortsAvailable, true, "NoConflict",
+		"the smee host ports are not used by another stack")
+
+	return nil
+}
+
+// smeeHostPorts returns the host ports Smee listens on in the port/protocol format.
+func smeeHostPorts(smee *v1alpha2.Smee) sets.Set[string] {
+	ports := boots.ListenPorts(smee)
+
+	return sets.New(
+		hostPort(ports.DHCP, corev1.ProtocolUDP),
+		hostPort(ports.HTTP, corev1.ProtocolTCP),
+		hostPort(ports.Syslog, corev1.ProtocolUDP),
+		hostPort(ports.TFTP, corev1.ProtocolUDP),
+	)
+}
+
+func hostPort(port int, protocol corev1.Protocol) string {
+	return fmt.Sprintf("%d/%s", port, protocol)
+}
+
+// olderStack returns whether stack a was created before stack b. Stacks created in the same second are ordered by
+// namespace and name, so that exactly one of two stacks is the older one.
+func olderStack(a, b *v1alpha2.Stack) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	if a.Namespace != b.Namespace {
+		return a.Namespace < b.Namespace
+	}
+
+	return a.Name < b.Name
+}
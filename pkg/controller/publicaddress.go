@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// nginxPodLabels select the pods of the nginx proxy deployment.
+var nginxPodLabels = ctrlruntimeclient.MatchingLabels{"app": "nginx-server"}
+
+// errPublicAddressPending is returned by the reconciliation while the public address of the stack can't be discovered
+// yet, e.g. because the nginx proxy isn't scheduled, so that the stack is requeued.
+var errPublicAddressPending = pendingf("waiting for the public address of the stack to be discovered")
+
+// publicIP returns the address the provisioned machines use to reach the stack services. It returns an empty address
+// if the address is not discovered yet.
+func (r *Reconciler) publicIP(ctx context.Context, stack *v1alpha2.Stack) (string, error) {
+	publicAddress := stack.Spec.PublicAddress
+	if publicAddress == nil {
+		publicAddress = &v1alpha2.PublicAddress{}
+	}
+
+	if publicAddress.IP != nil && *publicAddress.IP != "" {
+		return *publicAddress.IP, nil
+	}
+
+	switch publicAddress.Discovery {
+	case v1alpha2.PublicAddressDiscoveryLoadBalancer:
+		if publicAddress.LoadBalancerService == nil || *publicAddress.LoadBalancerService == "" {
+			return "", errors.New("loadBalancerService must be set to discover the public address from a LoadBalancer")
+		}
+
+		return r.loadBalancerIP(ctx, stack.Namespace, *publicAddress.LoadBalancerService)
+	case v1alpha2.PublicAddressDiscoveryNginxNode, "":
+		return r.nginxNodeIP(ctx, stack.Namespace)
+	default:
+		return "", fmt.Errorf("unknown public address discovery %q", publicAddress.Discovery)
+	}
+}
+
+// loadBalancerIP returns the ingress IP of the given LoadBalancer service.
+func (r *Reconciler) loadBalancerIP(ctx context.Context, namespace, name string) (string, error) {
+	service := &corev1.Service{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, service); err != nil {
+		if kerrors.IsNotFound(err) {
+			return "", nil
+		}
+
+		return "", fmt.Errorf("failed to get service %q: %w", name, err)
+	}
+
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return "", fmt.Errorf("service %q is of type %s, not %s", name, service.Spec.Type, corev1.ServiceTypeLoadBalancer)
+	}
+
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			return ingress.IP, nil
+		}
+	}
+
+	return "", nil
+}
+
+// nginxNodeIP returns the address of the node the nginx proxy of the given namespace is running on. External node addresses are preferred
+// over internal ones.
+func (r *Reconciler) nginxNodeIP(ctx context.Context, namespace string) (string, error) {
+	nodeName, err := r.nginxNodeName(ctx, namespace)
+	if err != nil || nodeName == "" {
+		return "", err
+	}
+
+	node := &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: nodeName}, node); err != nil {
+		return "", fmt.Errorf("failed to get node %q: %w", nodeName, err)
+	}
+
+	if ip := nodeAddress(node, corev1.NodeExternalIP); ip != "" {
+		return ip, nil
+	}
+
+	return nodeAddress(node, corev1.NodeInternalIP), nil
+}
+
+// nginxNodeName returns the name of the node the nginx proxy of the given namespace is scheduled on. It returns an empty name if the proxy
+// isn't scheduled yet. With several replicas, the node of the oldest ready pod is picked, so that the public address doesn't change
+// between reconciliations while the pods are stable.
+func (r *Reconciler) nginxNodeName(ctx context.Context, namespace string) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, ctrlruntimeclient.InNamespace(namespace), nginxPodLabels); err != nil {
+		return "", fmt.Errorf("failed to list nginx pods: %w", err)
+	}
+
+	var scheduled []corev1.Pod
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" && pod.DeletionTimestamp.IsZero() {
+			scheduled = append(scheduled, pod)
+		}
+	}
+
+	if len(scheduled) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(scheduled, func(i, j int) bool {
+		a, b := &scheduled[i], &scheduled[j]
+		if readyA, readyB := util.PodReady(a), util.PodReady(b); readyA != readyB {
+			return readyA
+		}
+
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+
+		return a.Name < b.Name
+	})
+
+	return scheduled[0].Spec.NodeName, nil
+}
+
+// isNginxPod returns whether the given object is a pod of the nginx proxy of a stack managed by the operator.
+func (r *Reconciler) isNginxPod(obj ctrlruntimeclient.Object) bool {
+	if r.namespace != "" && obj.GetNamespace() != r.namespace {
+		return false
+	}
+
+	for k, v := range nginxPodLabels {
+		if obj.GetLabels()[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+func nodeAddress(node *corev1.Node, addressType corev1.NodeAddressType) string {
+	for _, address := range node.Status.Addresses {
+		if address.Type == addressType {
+			return address.Address
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const testNamespace = "tinkerbell"
+
+// newTestReconciler returns a reconciler backed by a fake client holding the given objects.
+func newTestReconciler(t *testing.T, objs ...client.Object) *Reconciler {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1alpha2.Stack{}, &appsv1.Deployment{}, &batchv1.Job{}, &apiextensionsv1.CustomResourceDefinition{}).
+		Build()
+	c = interceptor.NewClient(c, interceptor.Funcs{Patch: applyAsCreateOrUpdate})
+
+	return &Reconciler{Client: c, apiReader: c, log: zap.NewNop().Sugar(), namespace: testNamespace}
+}
+
+// applyAsCreateOrUpdate emulates the server-side apply patches, which the fake client doesn't support, by creating or
+// replacing the object.
+func applyAsCreateOrUpdate(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+
+	existing, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		return c.Create(ctx, obj)
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+
+	return c.Update(ctx, obj)
+}
+
+func nginxPod(name, node string, created time.Time, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         testNamespace,
+			Labels:            nginxPodLabels,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestNginxNodeName(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := map[string]struct {
+		pods     []client.Object
+		expected string
+	}{
+		"no pods": {},
+		"unscheduled pod": {
+			pods: []client.Object{nginxPod("nginx-a", "", now, false)},
+		},
+		"scheduled pod which isn't ready": {
+			pods:     []client.Object{nginxPod("nginx-a", "node-a", now, false)},
+			expected: "node-a",
+		},
+		"ready pod before older pods which aren't ready": {
+			pods: []client.Object{
+				nginxPod("nginx-a", "node-a", now.Add(-time.Hour), false),
+				nginxPod("nginx-b", "node-b", now, true),
+			},
+			expected: "node-b",
+		},
+		"oldest ready pod": {
+			pods: []client.Object{
+				nginxPod("nginx-a", "node-a", now, true),
+				nginxPod("nginx-b", "node-b", now.Add(-time.Hour), true),
+				nginxPod("nginx-c", "node-c", now.Add(-time.Minute), true),
+			},
+			expected: "node-b",
+		},
+		"pods of the same age are ordered by name": {
+			pods: []client.Object{
+				nginxPod("nginx-c", "node-c", now, true),
+				nginxPod("nginx-a", "node-a", now, true),
+				nginxPod("nginx-b", "node-b", now, true),
+			},
+			expected: "node-a",
+		},
+		"pods of other namespaces are ignored": {
+			pods: []client.Object{
+				func() client.Object {
+					pod := nginxPod("nginx-a", "node-a", now.Add(-time.Hour), true)
+					pod.Namespace = "other"
+					return pod
+				}(),
+				nginxPod("nginx-b", "node-b", now, true),
+			},
+			expected: "node-b",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newTestReconciler(t, test.pods...)
+
+			// The pick doesn't depend on the order the pods are listed in.
+			for i := 0; i < 5; i++ {
+				node, err := r.nginxNodeName(context.Background(), testNamespace)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if node != test.expected {
+					t.Fatalf("expected node %q, got %q", test.expected, node)
+				}
+			}
+		})
+	}
+}
@@ -3,157 +3,251 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
 	"github.com/tinkerbell/operator/pkg/resources/boots"
 	"github.com/tinkerbell/operator/pkg/resources/hegel"
 	"github.com/tinkerbell/operator/pkg/resources/rufio"
 	"github.com/tinkerbell/operator/pkg/resources/tink"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func (r *Reconciler) ensureTinkerbellServiceAccounts(ctx context.Context) error {
-	if err := boots.CreateServiceAccount(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create boots service account: %v", err)
+func (r *Reconciler) ensureTinkerbellServiceAccounts(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config) error {
+	if stack.Spec.Services.Smee != nil {
+		if err := boots.CreateServiceAccount(ctx, r.Client, stack, cfg); err != nil {
+			return fmt.Errorf("failed to create boots service account: %v", err)
+		}
 	}
 
-	if err := hegel.CreateServiceAccount(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create hegel service account: %v", err)
+	if stack.Spec.Services.Hegel != nil {
+		if err := hegel.CreateServiceAccount(ctx, r.Client, stack, cfg); err != nil {
+			return fmt.Errorf("failed to create hegel service account: %v", err)
+		}
 	}
 
-	if err := rufio.CreateServiceAccount(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create rufio service account: %v", err)
+	if stack.Spec.Services.Rufio != nil {
+		if err := rufio.CreateServiceAccount(ctx, r.Client, stack, cfg); err != nil {
+			return fmt.Errorf("failed to create rufio service account: %v", err)
+		}
 	}
 
-	if err := tink.CreateTinkControllerServiceAccount(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateTinkControllerServiceAccount(ctx, r.Client, stack, cfg); err != nil {
 		return fmt.Errorf("failed to create tink controller service account: %v", err)
 	}
 
-	if err := tink.CreateTinkServerServiceAccount(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateTinkServerServiceAccount(ctx, r.Client, stack, cfg); err != nil {
 		return fmt.Errorf("failed to create tink server service account: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellClusterRole(ctx context.Context) error {
-	if err := boots.CreateClusterRole(ctx, r.Client); err != nil {
-		return fmt.Errorf("failed to create boots cluster role: %v", err)
+func (r *Reconciler) ensureTinkerbellClusterRole(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Smee != nil {
+		if err := boots.CreateClusterRole(ctx, r.Client, stack); err != nil {
+			return fmt.Errorf("failed to create boots cluster role: %v", err)
+		}
 	}
 
-	if err := rufio.CreateClusterRole(ctx, r.Client); err != nil {
-		return fmt.Errorf("failed to create rufio cluster role: %v", err)
+	if stack.Spec.Services.Rufio != nil {
+		if err := rufio.CreateClusterRole(ctx, r.Client, stack); err != nil {
+			return fmt.Errorf("failed to create rufio cluster role: %v", err)
+		}
 	}
 
-	if err := tink.CreateTinkControllerClusterRole(ctx, r.Client); err != nil {
+	if err := tink.CreateTinkControllerClusterRole(ctx, r.Client, stack); err != nil {
 		return fmt.Errorf("failed to create tink controller cluster role: %v", err)
 	}
 
-	if err := tink.CreateTinkServerClusterRole(ctx, r.Client); err != nil {
+	if err := tink.CreateTinkServerClusterRole(ctx, r.Client, stack); err != nil {
 		return fmt.Errorf("failed to create tink server cluster role: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellClusterRoleBinding(ctx context.Context) error {
-	if err := boots.CreateClusterRoleBinding(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create boots cluster role binding: %v", err)
+func (r *Reconciler) ensureTinkerbellClusterRoleBinding(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Smee != nil {
+		if err := boots.CreateClusterRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create boots cluster role binding: %v", err)
+		}
 	}
 
-	if err := rufio.CreateClusterRoleBinding(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create rufio cluster role binding: %v", err)
+	if stack.Spec.Services.Rufio != nil {
+		if err := rufio.CreateClusterRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create rufio cluster role binding: %v", err)
+		}
 	}
 
-	if err := tink.CreateTinkControllerClusterRoleBinding(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateTinkControllerClusterRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
 		return fmt.Errorf("failed to create tink controller cluster role binding: %v", err)
 	}
 
-	if err := tink.CreateTinkServerClusterRoleBinding(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateTinkServerClusterRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
 		return fmt.Errorf("failed to create tink server cluster role binding: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellRole(ctx context.Context) error {
-	if err := hegel.CreateRole(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create hegel role: %v", err)
+// ensureLegacyClusterRBACRemoved deletes the cluster roles and bindings of the stack which are still named after the
+// component only. They were created before the cluster-scoped objects got per stack names.
+func (r *Reconciler) ensureLegacyClusterRBACRemoved(ctx context.Context, stack *v1alpha2.Stack) error {
+	suffix := util.ClusterScopedName(stack, "")
+
+	lists := []client.ObjectList{&rbacv1.ClusterRoleBindingList{}, &rbacv1.ClusterRoleList{}}
+	for _, list := range lists {
+		if err := r.List(ctx, list, client.MatchingLabels(util.StackLabels(stack))); err != nil {
+			return fmt.Errorf("failed to list %T: %v", list, err)
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return fmt.Errorf("failed to extract %T items: %v", list, err)
+		}
+
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok || strings.HasSuffix(obj.GetName(), suffix) {
+				continue
+			}
+
+			if err := r.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %T %q: %v", obj, obj.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) ensureTinkerbellRole(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Hegel != nil {
+		if err := hegel.CreateRole(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create hegel role: %v", err)
+		}
 	}
 
-	if err := rufio.CreateRole(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create rufio role: %v", err)
+	if stack.Spec.Services.Rufio != nil {
+		if err := rufio.CreateRole(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create rufio role: %v", err)
+		}
 	}
 
-	if err := tink.CreateRole(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateRole(ctx, r.Client, stack, stack.Namespace); err != nil {
 		return fmt.Errorf("failed to create tink leader election role: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellRoleBinding(ctx context.Context) error {
-	if err := hegel.CreateRoleBinding(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create hegel role binding: %v", err)
+func (r *Reconciler) ensureTinkerbellRoleBinding(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Hegel != nil {
+		if err := hegel.CreateRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create hegel role binding: %v", err)
+		}
 	}
 
-	if err := rufio.CreateRoleBinding(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create rufio role binding: %v", err)
+	if stack.Spec.Services.Rufio != nil {
+		if err := rufio.CreateRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create rufio role binding: %v", err)
+		}
 	}
 
-	if err := tink.CreateRoleBinding(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateRoleBinding(ctx, r.Client, stack, stack.Namespace); err != nil {
 		return fmt.Errorf("failed to create tink leader election role binding: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellServices(ctx context.Context) error {
-	if err := boots.CreateService(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create boots service: %v", err)
+func (r *Reconciler) ensureTinkerbellServices(ctx context.Context, stack *v1alpha2.Stack) error {
+	if stack.Spec.Services.Smee != nil {
+		if err := boots.CreateService(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create boots service: %v", err)
+		}
 	}
 
-	if err := hegel.CreateService(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create hegel service: %v", err)
+	if stack.Spec.Services.Hegel != nil {
+		if err := hegel.CreateService(ctx, r.Client, stack, stack.Namespace); err != nil {
+			return fmt.Errorf("failed to create hegel service: %v", err)
+		}
 	}
 
-	if err := tink.CreateService(ctx, r.Client, r.namespace); err != nil {
+	if err := tink.CreateService(ctx, r.Client, stack, stack.Namespace); err != nil {
 		return fmt.Errorf("failed to create tink service: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellDeployments(ctx context.Context) error {
-	if err := boots.CreateDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create boots deployment: %v", err)
-	}
+// ensureHookStorage creates the PersistentVolumeClaim of the Hook artifacts if the stack stores them in one, otherwise
+// it removes a claim left over from a previous storage.
+func (r *Reconciler) ensureHookStorage(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config) error {
+	if tink.HookStorage(stack).Type == v1alpha2.HookStorageTypePersistentVolumeClaim {
+		if err := tink.CreateHookArtifactsPVC(ctx, r.Client, stack, cfg); err != nil {
+			return fmt.Errorf("failed to create hook artifacts persistent volume claim: %v", err)
+		}
 
-	if err := hegel.CreateDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create hegel deployment: %v", err)
+		return nil
 	}
 
-	if err := rufio.CreateDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create rufio deployment: %v", err)
-	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: stack.Namespace, Name: tink.HookArtifactsPVCName}, pvc); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
 
-	if err := tink.CreateTinkControllerDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create tink controller deployment: %v", err)
+		return fmt.Errorf("failed to get hook artifacts persistent volume claim: %v", err)
 	}
 
-	if err := tink.CreateTinkServerDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create tink server deployment: %v", err)
+	if !metav1.IsControlledBy(pvc, stack) || !pvc.DeletionTimestamp.IsZero() {
+		return nil
 	}
 
-	if err := tink.CreateNginxDeployment(ctx, r.Client, r.namespace); err != nil {
-		return fmt.Errorf("failed to create tink stack deployment: %v", err)
+	if err := r.Delete(ctx, pvc); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete hook artifacts persistent volume claim: %v", err)
 	}
 
 	return nil
 }
 
-func (r *Reconciler) ensureTinkerbellConfigMaps(ctx context.Context) error {
-	if err := tink.CreateNginxConfigMap(ctx, r.Client, r.clusterDNS, r.namespace); err != nil {
+func (r *Reconciler) ensureTinkerbellConfigMaps(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config) error {
+	if err := tink.CreateNginxConfigMap(ctx, r.Client, stack, cfg); err != nil {
 		return fmt.Errorf("failed to create stack nginx configmap: %v", err)
 	}
 
 	return nil
 }
+
+func (r *Reconciler) ensureDisabledComponentsRemoved(ctx context.Context, stack *v1alpha2.Stack) error {
+	var disabled []string
+	if stack.Spec.Services.Smee == nil {
+		disabled = append(disabled, boots.ComponentName)
+	}
+	if stack.Spec.Services.Hegel == nil {
+		disabled = append(disabled, hegel.ComponentName)
+	}
+	if stack.Spec.Services.Rufio == nil {
+		disabled = append(disabled, rufio.ComponentName)
+	}
+
+	for _, component := range disabled {
+		if _, err := r.deleteObjects(ctx, stack, util.ComponentLabels(stack, component)); err != nil {
+			return fmt.Errorf("failed to remove %s resources: %v", component, err)
+		}
+	}
+
+	return nil
+}
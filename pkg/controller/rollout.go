@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	appsv1 "k8s.io/api/apps/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// failedConfigAnnotation is set on a rolled back deployment and holds the checksum of the stack configuration whose
+	// rollout failed, so that the configuration isn't applied again until it changes.
+	failedConfigAnnotation = "tinkerbell.org/failed-config"
+	// rolledBackToAnnotation is set on a rolled back deployment and holds the revision it was rolled back to.
+	rolledBackToAnnotation = "tinkerbell.org/rolled-back-to"
+	// revisionAnnotation holds the revision of a deployment and its replica sets, it is set by the deployment controller.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+
+	// pendingRequeueInterval is the interval a stack is reconciled at while it waits for a rollout.
+	pendingRequeueInterval = 10 * time.Second
+)
+
+// pendingError is returned by the reconciliation while it waits for the cluster to converge, e.g. for a rollout to
+// complete. It isn't a failure, the stack is requeued without backoff and reported as reconciled.
+type pendingError struct {
+	message string
+}
+
+func (e *pendingError) Error() string {
+	return e.message
+}
+
+func pendingf(format string, args ...interface{}) error {
+	return &pendingError{message: fmt.Sprintf(format, args...)}
+}
+
+// ensureTinkerbellDeployments rolls out the stack components one after another in the order of stackComponents. A
+// component is only applied once the components before it are rolled out and available, so that an upgrade never runs
+// a component against older versions of the components it depends on. Smee is skipped unless deploySmee is set.
+func (r *Reconciler) ensureTinkerbellDeployments(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config, deploySmee bool) error {
+	checksum, err := stackConfigChecksum(stack, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range stackComponents(stack) {
+		// Smee hands out the public address to the provisioned machines, so it is deployed only once the address is
+		// known.
+		if c.name == boots.ComponentName && (!deploySmee || cfg.PublicIP == "") {
+			continue
+		}
+
+		if err := r.rollout(ctx, stack, cfg, c, checksum); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollout applies the deployment of the given component and returns a pendingError until its rollout is complete. A failed
+// rollout is rolled back to the previous revision if the stack enables the automatic rollback.
+func (r *Reconciler) rollout(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config, c component, checksum string) error {
+	key := types.NamespacedName{Namespace: stack.Namespace, Name: c.deployment}
+
+	// The deployments are read from the API server, the cache might not have observed the last rollback or the applied
+	// changes yet.
+	existing := &appsv1.Deployment{}
+	if err := r.apiReader.Get(ctx, key, existing); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get %s deployment: %v", c.name, err)
+		}
+	} else if existing.Annotations[failedConfigAnnotation] == checksum {
+		return fmt.Errorf("rollout of %s failed and was rolled back to revision %s, the upgrade is halted until the stack changes",
+			c.name, existing.Annotations[rolledBackToAnnotation])
+	}
+
+	if err := c.create(ctx, r.Client, stack, cfg); err != nil {
+		return fmt.Errorf("failed to create %s deployment: %v", c.name, err)
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.apiReader.Get(ctx, key, deployment); err != nil {
+		return fmt.Errorf("failed to get %s deployment: %v", c.name, err)
+	}
+
+	if _, ok := deployment.Annotations[failedConfigAnnotation]; ok {
+		patch := client.MergeFrom(deployment.DeepCopy())
+		delete(deployment.Annotations, failedConfigAnnotation)
+		delete(deployment.Annotations, rolledBackToAnnotation)
+		if err := r.Patch(ctx, deployment, patch); err != nil {
+			return fmt.Errorf("failed to clear the rollback of %s deployment: %v", c.name, err)
+		}
+	}
+
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return pendingf("waiting for the rollout of %s to start", c.name)
+	}
+
+	if degraded, reason, message := deploymentDegraded(deployment); degraded {
+		if stack.Spec.Upgrade == nil || !stack.Spec.Upgrade.AutoRollback {
+			return fmt.Errorf("rollout of %s failed, the upgrade is halted: %s: %s", c.name, reason, message)
+		}
+
+		revision, err := r.rollback(ctx, deployment, checksum)
+		if err != nil {
+			return fmt.Errorf("rollout of %s failed and can't be rolled back: %v", c.name, err)
+		}
+
+		r.log.Infof("rolled back %s of stack %s/%s to revision %s", c.name, stack.Namespace, stack.Name, revision)
+
+		return fmt.Errorf("rollout of %s failed, rolled back to revision %s", c.name, revision)
+	}
+
+	if progressing, _, message := deploymentProgressing(deployment); progressing {
+		return pendingf("waiting for the rollout of %s: %s", c.name, message)
+	}
+
+	if available, _, message := deploymentAvailable(deployment); !available {
+		return pendingf("waiting for %s to become available: %s", c.name, message)
+	}
+
+	return nil
+}
+
+// rollback restores the pod template of the previous revision of the given deployment, like kubectl rollout undo, and
+// records the failed configuration on the deployment. It returns the revision the deployment is rolled back to.
+func (r *Reconciler) rollback(ctx context.Context, deployment *appsv1.Deployment, checksum string) (string, error) {
+	current, err := strconv.ParseInt(deployment.Annotations[revisionAnnotation], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the revision of the deployment: %w", err)
+	}
+
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := r.apiReader.List(ctx, replicaSets, client.InNamespace(deployment.Namespace), client.MatchingLabels(deployment.Spec.Selector.MatchLabels)); err != nil {
+		return "", fmt.Errorf("failed to list replica sets: %w", err)
+	}
+
+	var previous *appsv1.ReplicaSet
+	var previousRevision int64
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if !metav1.IsControlledBy(rs, deployment) {
+			continue
+		}
+
+		revision, err := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+		if err != nil || revision >= current || revision <= previousRevision {
+			continue
+		}
+
+		previous, previousRevision = rs, revision
+	}
+
+	if previous == nil {
+		return "", errors.New("no previous revision found")
+	}
+
+	template := previous.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+
+	revision := strconv.FormatInt(previousRevision, 10)
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	deployment.Spec.Template = *template
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[failedConfigAnnotation] = checksum
+	deployment.Annotations[rolledBackToAnnotation] = revision
+
+	if err := r.Patch(ctx, deployment, patch); err != nil {
+		return "", fmt.Errorf("failed to patch deployment: %w", err)
+	}
+
+	return revision, nil
+}
+
+// stackConfigChecksum returns a checksum of everything the stack deployments are rendered from.
+func stackConfigChecksum(stack *v1alpha2.Stack, cfg resources.Config) (string, error) {
+	spec, err := json.Marshal(stack.Spec)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stack spec: %v", err)
+	}
+
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal stack configuration: %v", err)
+	}
+
+	return util.Checksum(spec, config), nil
+}
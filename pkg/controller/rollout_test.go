@@ -0,0 +1,493 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/hegel"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ptr "k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const testChecksum = "checksum"
+
+var testSelector = map[string]string{"app": "hegel"}
+
+// testDeployment returns a deployment whose rollout of the given generation is complete and available.
+func testDeployment(name string, generation int64) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   testNamespace,
+			UID:         types.UID(name + "-uid"),
+			Generation:  generation,
+			Annotations: map[string]string{revisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Int32(1),
+			Selector: &metav1.LabelSelector{MatchLabels: testSelector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: testSelector},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: name, Image: "hegel:v2"}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: generation,
+			Replicas:           1,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+			Conditions: []appsv1.DeploymentCondition{
+				{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue, Reason: "MinimumReplicasAvailable"},
+				{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable"},
+			},
+		},
+	}
+}
+
+// testReplicaSet returns a replica set of the given revision of the given deployment.
+func testReplicaSet(deployment *appsv1.Deployment, revision, image string) *appsv1.ReplicaSet {
+	labels := map[string]string{"app": "hegel", appsv1.DefaultDeploymentUniqueLabelKey: "hash-" + revision}
+
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            deployment.Name + "-" + revision,
+			Namespace:       testNamespace,
+			Labels:          labels,
+			Annotations:     map[string]string{revisionAnnotation: revision},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(deployment, appsv1.SchemeGroupVersion.WithKind("Deployment"))},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: deployment.Name, Image: image}}},
+			},
+		},
+	}
+}
+
+func withDeploymentStatus(deployment *appsv1.Deployment, update func(*appsv1.DeploymentStatus)) *appsv1.Deployment {
+	update(&deployment.Status)
+	return deployment
+}
+
+func withAnnotations(deployment *appsv1.Deployment, annotations map[string]string) *appsv1.Deployment {
+	for k, v := range annotations {
+		deployment.Annotations[k] = v
+	}
+
+	return deployment
+}
+
+func progressDeadlineExceeded(status *appsv1.DeploymentStatus) {
+	status.Conditions[1] = appsv1.DeploymentCondition{
+		Type:    appsv1.DeploymentProgressing,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ProgressDeadlineExceeded",
+		Message: `ReplicaSet "hegel-2" has timed out progressing.`,
+	}
+}
+
+func TestRollout(t *testing.T) {
+	autoRollback := &v1alpha2.UpgradeStrategy{AutoRollback: true}
+
+	tests := map[string]struct {
+		objs         []client.Object
+		upgrade      *v1alpha2.UpgradeStrategy
+		expectCreate bool
+		expectErr    string
+		// expectPending is set if the error is expected to be a pendingError.
+		expectPending bool
+		// expected checks the deployment after the rollout.
+		expected func(*testing.T, *appsv1.Deployment)
+	}{
+		"deployment not created yet": {
+			expectCreate:  true,
+			expectErr:     "waiting for the rollout of hegel to start",
+			expectPending: true,
+		},
+		"spec change not observed yet": {
+			objs: []client.Object{withDeploymentStatus(testDeployment("hegel", 2), func(status *appsv1.DeploymentStatus) {
+				status.ObservedGeneration = 1
+			})},
+			expectCreate:  true,
+			expectErr:     "waiting for the rollout of hegel to start",
+			expectPending: true,
+		},
+		"rolling out": {
+			objs: []client.Object{withDeploymentStatus(testDeployment("hegel", 2), func(status *appsv1.DeploymentStatus) {
+				status.Replicas, status.UpdatedReplicas = 2, 1
+			})},
+			expectCreate:  true,
+			expectErr:     "waiting for the rollout of hegel: 1 old replicas pending termination",
+			expectPending: true,
+		},
+		"not available": {
+			objs: []client.Object{withDeploymentStatus(testDeployment("hegel", 2), func(status *appsv1.DeploymentStatus) {
+				status.Conditions[0] = appsv1.DeploymentCondition{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse, Message: "Deployment does not have minimum availability."}
+			})},
+			expectCreate:  true,
+			expectErr:     "waiting for hegel to become available",
+			expectPending: true,
+		},
+		"rollout complete": {
+			objs:         []client.Object{testDeployment("hegel", 2)},
+			expectCreate: true,
+		},
+		"degraded without automatic rollback": {
+			objs:         []client.Object{withDeploymentStatus(testDeployment("hegel", 2), progressDeadlineExceeded)},
+			expectCreate: true,
+			expectErr:    "rollout of hegel failed, the upgrade is halted: ProgressDeadlineExceeded",
+			expected: func(t *testing.T, deployment *appsv1.Deployment) {
+				if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "hegel:v2" {
+					t.Errorf("expected the deployment not to be rolled back, got image %q", image)
+				}
+			},
+		},
+		"degraded with automatic rollback": {
+			objs: func() []client.Object {
+				deployment := withDeploymentStatus(testDeployment("hegel", 2), progressDeadlineExceeded)
+				other := testDeployment("other", 1)
+
+				return []client.Object{
+					deployment,
+					testReplicaSet(deployment, "2", "hegel:v2"),
+					testReplicaSet(deployment, "1", "hegel:v1"),
+					testReplicaSet(deployment, "0", "hegel:v0"),
+					// Replica sets of other deployments matching the selector are ignored.
+					testReplicaSet(other, "1", "other:v1"),
+				}
+			}(),
+			upgrade:      autoRollback,
+			expectCreate: true,
+			expectErr:    "rollout of hegel failed, rolled back to revision 1",
+			expected: func(t *testing.T, deployment *appsv1.Deployment) {
+				if image := deployment.Spec.Template.Spec.Containers[0].Image; image != "hegel:v1" {
+					t.Errorf("expected the deployment to be rolled back to the image of revision 1, got %q", image)
+				}
+				if _, ok := deployment.Spec.Template.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+					t.Errorf("expected the pod template hash label of the replica set to be dropped, got %v", deployment.Spec.Template.Labels)
+				}
+				if checksum := deployment.Annotations[failedConfigAnnotation]; checksum != testChecksum {
+					t.Errorf("expected the failed configuration %q to be recorded, got %q", testChecksum, checksum)
+				}
+				if revision := deployment.Annotations[rolledBackToAnnotation]; revision != "1" {
+					t.Errorf("expected the rolled back revision 1 to be recorded, got %q", revision)
+				}
+			},
+		},
+		"degraded with automatic rollback and no previous revision": {
+			objs: func() []client.Object {
+				deployment := withDeploymentStatus(testDeployment("hegel", 2), progressDeadlineExceeded)
+				return []client.Object{deployment, testReplicaSet(deployment, "2", "hegel:v2")}
+			}(),
+			upgrade:      autoRollback,
+			expectCreate: true,
+			expectErr:    "rollout of hegel failed and can't be rolled back: no previous revision found",
+		},
+		"rolled back configuration": {
+			objs: []client.Object{withAnnotations(withDeploymentStatus(testDeployment("hegel", 3), progressDeadlineExceeded), map[string]string{
+				failedConfigAnnotation: testChecksum,
+				rolledBackToAnnotation: "1",
+			})},
+			upgrade:   autoRollback,
+			expectErr: "rollout of hegel failed and was rolled back to revision 1, the upgrade is halted until the stack changes",
+		},
+		"rollback cleared by a configuration change": {
+			objs: []client.Object{withAnnotations(testDeployment("hegel", 3), map[string]string{
+				failedConfigAnnotation: "previous-checksum",
+				rolledBackToAnnotation: "1",
+			})},
+			upgrade:      autoRollback,
+			expectCreate: true,
+			expected: func(t *testing.T, deployment *appsv1.Deployment) {
+				if _, ok := deployment.Annotations[failedConfigAnnotation]; ok {
+					t.Errorf("expected the failed configuration to be cleared, got %v", deployment.Annotations)
+				}
+				if _, ok := deployment.Annotations[rolledBackToAnnotation]; ok {
+					t.Errorf("expected the rolled back revision to be cleared, got %v", deployment.Annotations)
+				}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			r := newTestReconciler(t, test.objs...)
+			stack := testStack()
+			stack.Spec.Upgrade = test.upgrade
+
+			created := false
+			c := component{
+				name:       hegel.ComponentName,
+				deployment: "hegel",
+				// The deployment is created as the API server would, an existing deployment is left as is.
+				create: func(ctx context.Context, c client.Client, _ *v1alpha2.Stack, _ resources.Config) error {
+					created = true
+
+					deployment := testDeployment("hegel", 1)
+					deployment.Status = appsv1.DeploymentStatus{}
+					if err := c.Create(ctx, deployment); err != nil && !kerrors.IsAlreadyExists(err) {
+						return err
+					}
+
+					return nil
+				},
+			}
+
+			err := r.rollout(ctx, stack, resources.Config{}, c, testChecksum)
+			switch {
+			case test.expectErr == "" && err != nil:
+				t.Fatalf("expected no error, got %v", err)
+			case test.expectErr != "" && (err == nil || !strings.Contains(err.Error(), test.expectErr)):
+				t.Fatalf("expected an error containing %q, got %v", test.expectErr, err)
+			}
+
+			var pending *pendingError
+			if errors.As(err, &pending) != test.expectPending {
+				t.Errorf("expected a pending error %t, got %v", test.expectPending, err)
+			}
+
+			if created != test.expectCreate {
+				t.Errorf("expected the deployment to be applied %t, got %t", test.expectCreate, created)
+			}
+
+			if test.expected != nil {
+				deployment := &appsv1.Deployment{}
+				if err := r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: "hegel"}, deployment); err != nil {
+					t.Fatal(err)
+				}
+				test.expected(t, deployment)
+			}
+		})
+	}
+}
+
+// completeRollout marks the rollout of the given deployment as complete and available.
+func completeRollout(t *testing.T, r *Reconciler, name string) {
+	t.Helper()
+
+	ctx := context.Background()
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	deployment.Status = testDeployment(name, deployment.Generation).Status
+	if err := r.Status().Update(ctx, deployment); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deploymentNames(t *testing.T, r *Reconciler) []string {
+	t.Helper()
+
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(context.Background(), deployments, client.InNamespace(testNamespace)); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, deployment := range deployments.Items {
+		names = append(names, deployment.Name)
+	}
+
+	return names
+}
+
+func TestEnsureTinkerbellDeployments(t *testing.T) {
+	rel, err := release.Get("v0.8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	stack := testStack()
+	r := newTestReconciler(t, stack)
+	cfg := resources.Config{Namespace: testNamespace, Release: rel}
+
+	// The components are applied one after another, each once the components before it are rolled out.
+	components := stackComponents(stack)
+	for i, c := range components {
+		err := r.ensureTinkerbellDeployments(ctx, stack, cfg, true)
+
+		var pending *pendingError
+		if !errors.As(err, &pending) || !strings.Contains(err.Error(), c.name) {
+			t.Fatalf("expected to wait for the rollout of %s, got %v", c.name, err)
+		}
+
+		if names := deploymentNames(t, r); len(names) != i+1 {
+			t.Fatalf("expected the deployments of the first %d components, got %v", i+1, names)
+		}
+
+		completeRollout(t, r, c.deployment)
+	}
+
+	if err := r.ensureTinkerbellDeployments(ctx, stack, cfg, true); err != nil {
+		t.Fatalf("expected every component to be rolled out, got %v", err)
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	stack := testStack()
+	stack.Generation = 2
+	stack.Spec.Services.Hegel = &v1alpha2.Hegel{}
+
+	rolledBack := withAnnotations(testDeployment(hegel.DeploymentName, 3), map[string]string{rolledBackToAnnotation: "1"})
+	deployments := []client.Object{
+		testDeployment(tink.TinkServerDeploymentName, 1),
+		withDeploymentStatus(testDeployment(tink.TinkControllerDeploymentName, 2), func(status *appsv1.DeploymentStatus) {
+			status.ObservedGeneration = 1
+		}),
+	}
+
+	tests := map[string]struct {
+		objs                []client.Object
+		reconcileErr        error
+		expectedReconciled  metav1.ConditionStatus
+		expectedReason      string
+		expectedAvailable   string
+		expectedProgressing string
+		expectedDegraded    string
+		// expectedComponents maps the component names to the reason of their Available condition.
+		expectedComponents map[string]string
+	}{
+		"pending": {
+			objs:                deployments,
+			reconcileErr:        pendingf("waiting for the rollout of tink-controller to start"),
+			expectedReconciled:  metav1.ConditionTrue,
+			expectedReason:      "ReconcilePending",
+			expectedAvailable:   "2/4 components available",
+			expectedProgressing: "progressing components: tink-controller, hegel, nginx",
+			expectedDegraded:    "no components degraded",
+			expectedComponents: map[string]string{
+				tink.TinkServerComponentName:     "MinimumReplicasAvailable",
+				tink.TinkControllerComponentName: "MinimumReplicasAvailable",
+				hegel.ComponentName:              "Pending",
+				tink.NginxComponentName:          "Pending",
+			},
+		},
+		"failed": {
+			objs:                append([]client.Object{rolledBack}, deployments...),
+			reconcileErr:        errors.New("rollout of hegel failed, rolled back to revision 1"),
+			expectedReconciled:  metav1.ConditionFalse,
+			expectedReason:      "ReconcileFailed",
+			expectedAvailable:   "3/4 components available",
+			expectedProgressing: "progressing components: tink-controller, nginx",
+			expectedDegraded:    "degraded components: hegel",
+			expectedComponents: map[string]string{
+				tink.TinkServerComponentName:     "MinimumReplicasAvailable",
+				tink.TinkControllerComponentName: "MinimumReplicasAvailable",
+				hegel.ComponentName:              "MinimumReplicasAvailable",
+				tink.NginxComponentName:          "Pending",
+			},
+		},
+		"no deployments created yet": {
+			expectedReconciled:  metav1.ConditionTrue,
+			expectedReason:      "ReconcileSucceeded",
+			expectedAvailable:   "0/4 components available",
+			expectedProgressing: "progressing components: tink-server, tink-controller, hegel, nginx",
+			expectedDegraded:    "no components degraded",
+			expectedComponents: map[string]string{
+				tink.TinkServerComponentName:     "Pending",
+				tink.TinkControllerComponentName: "Pending",
+				hegel.ComponentName:              "Pending",
+				tink.NginxComponentName:          "Pending",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			original := stack.DeepCopy()
+			r := newTestReconciler(t, append([]client.Object{original.DeepCopy()}, test.objs...)...)
+
+			if err := r.updateStatus(ctx, original, stack.DeepCopy(), test.reconcileErr); err != nil {
+				t.Fatal(err)
+			}
+
+			updated := &v1alpha2.Stack{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(stack), updated); err != nil {
+				t.Fatal(err)
+			}
+
+			if updated.Status.ObservedGeneration != stack.Generation {
+				t.Errorf("expected the observed generation %d, got %d", stack.Generation, updated.Status.ObservedGeneration)
+			}
+
+			reconciled := meta.FindStatusCondition(updated.Status.Conditions, v1alpha2.ConditionReconciled)
+			if reconciled == nil || reconciled.Status != test.expectedReconciled || reconciled.Reason != test.expectedReason {
+				t.Errorf("expected a Reconciled condition %s with reason %q, got %+v", test.expectedReconciled, test.expectedReason, reconciled)
+			}
+
+			for condType, expected := range map[string]string{
+				v1alpha2.ConditionAvailable:   test.expectedAvailable,
+				v1alpha2.ConditionProgressing: test.expectedProgressing,
+				v1alpha2.ConditionDegraded:    test.expectedDegraded,
+			} {
+				if cond := meta.FindStatusCondition(updated.Status.Conditions, condType); cond == nil || cond.Message != expected {
+					t.Errorf("expected a %s condition with message %q, got %+v", condType, expected, cond)
+				}
+			}
+
+			if len(updated.Status.Components) != len(test.expectedComponents) {
+				t.Fatalf("expected %d components, got %+v", len(test.expectedComponents), updated.Status.Components)
+			}
+			for _, component := range updated.Status.Components {
+				cond := meta.FindStatusCondition(component.Conditions, v1alpha2.ConditionAvailable)
+				if expected := test.expectedComponents[component.Name]; cond == nil || cond.Reason != expected {
+					t.Errorf("expected the Available condition of %s to have reason %q, got %+v", component.Name, expected, cond)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcilePending(t *testing.T) {
+	ctx := context.Background()
+	stack := testStack()
+	stack.Spec.PublicAddress = &v1alpha2.PublicAddress{IP: ptr.String("192.168.1.10")}
+	r := newTestReconciler(t, stack)
+	r.clusterDNS = "10.96.0.10"
+
+	// The CRDs applied by the fake client are never established.
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(stack)})
+	if err != nil {
+		t.Fatalf("expected no error while waiting, got %v", err)
+	}
+
+	if result.RequeueAfter != pendingRequeueInterval {
+		t.Errorf("expected a requeue after %v, got %+v", pendingRequeueInterval, result)
+	}
+
+	updated := &v1alpha2.Stack{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(stack), updated); err != nil {
+		t.Fatal(err)
+	}
+
+	cond := meta.FindStatusCondition(updated.Status.Conditions, v1alpha2.ConditionReconciled)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.Reason != "ReconcilePending" || !strings.Contains(cond.Message, "waiting for the CRDs to be established") {
+		t.Errorf("expected a pending Reconciled condition, got %+v", cond)
+	}
+
+	if cond := meta.FindStatusCondition(updated.Status.Conditions, v1alpha2.ConditionCRDsEstablished); cond == nil || cond.Reason != "Pending" {
+		t.Errorf("expected a pending CRDsEstablished condition, got %+v", cond)
+	}
+}
@@ -0,0 +1,238 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+	"github.com/tinkerbell/operator/pkg/resources/hegel"
+	"github.com/tinkerbell/operator/pkg/resources/rufio"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// component maps a stack component to the deployment running it.
+type component struct {
+	name       string
+	deployment string
+	create     func(context.Context, client.Client, *v1alpha2.Stack, resources.Config) error
+}
+
+// stackComponents returns the components enabled in the given stack in the order they are rolled out. The Tinkerbell
+// API served by tink-server comes first, the services depending on it follow and the nginx proxy exposing them comes
+// last.
+func stackComponents(stack *v1alpha2.Stack) []component {
+	components := []component{
+		{name: tink.TinkServerComponentName, deployment: tink.TinkServerDeploymentName, create: tink.CreateTinkServerDeployment},
+		{name: tink.TinkControllerComponentName, deployment: tink.TinkControllerDeploymentName, create: tink.CreateTinkControllerDeployment},
+	}
+	if stack.Spec.Services.Hegel != nil {
+		components = append(components, component{name: hegel.ComponentName, deployment: hegel.DeploymentName, create: hegel.CreateDeployment})
+	}
+	if stack.Spec.Services.Rufio != nil {
+		components = append(components, component{name: rufio.ComponentName, deployment: rufio.DeploymentName, create: rufio.CreateDeployment})
+	}
+	if stack.Spec.Services.Smee != nil {
+		components = append(components, component{name: boots.ComponentName, deployment: boots.DeploymentName, create: boots.CreateDeployment})
+	}
+
+	return append(components,
+		component{name: tink.NginxComponentName, deployment: tink.NginxDeploymentName, create: tink.CreateNginxDeployment},
+	)
+}
+
+// updateStatus computes the stack status from the deployments the operator owns and the result of the last
+// reconciliation and patches it.
+func (r *Reconciler) updateStatus(ctx context.Context, original, stack *v1alpha2.Stack, reconcileErr error) error {
+	var pending *pendingError
+	switch {
+	case errors.As(reconcileErr, &pending):
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionReconciled, true, "ReconcilePending", pending.Error())
+	case reconcileErr != nil:
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionReconciled, false, "ReconcileFailed", reconcileErr.Error())
+	default:
+		setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionReconciled, true, "ReconcileSucceeded", "all stack resources are reconciled")
+	}
+
+	var available, progressing, degraded []string
+	components := make([]v1alpha2.ComponentStatus, 0, len(stackComponents(stack)))
+
+	for _, c := range stackComponents(stack) {
+		status, err := r.componentStatus(ctx, stack, c)
+		if err != nil {
+			return err
+		}
+
+		if meta.IsStatusConditionTrue(status.Conditions, v1alpha2.ConditionAvailable) {
+			available = append(available, c.name)
+		}
+		if meta.IsStatusConditionTrue(status.Conditions, v1alpha2.ConditionProgressing) {
+			progressing = append(progressing, c.name)
+		}
+		if meta.IsStatusConditionTrue(status.Conditions, v1alpha2.ConditionDegraded) {
+			degraded = append(degraded, c.name)
+		}
+
+		components = append(components, status)
+	}
+
+	stack.Status.Components = components
+	stack.Status.ObservedGeneration = stack.Generation
+
+	total := len(components)
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionAvailable, len(available) == total,
+		"ComponentsAvailable", fmt.Sprintf("%d/%d components available", len(available), total))
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionProgressing, len(progressing) > 0,
+		"ComponentsProgressing", componentsMessage("progressing", progressing))
+	setCondition(&stack.Status.Conditions, stack.Generation, v1alpha2.ConditionDegraded, len(degraded) > 0,
+		"ComponentsDegraded", componentsMessage("degraded", degraded))
+
+	if err := r.Status().Patch(ctx, stack, client.MergeFrom(original)); err != nil {
+		return fmt.Errorf("failed to patch stack status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Reconciler) componentStatus(ctx context.Context, stack *v1alpha2.Stack, c component) (v1alpha2.ComponentStatus, error) {
+	status := v1alpha2.ComponentStatus{Name: c.name}
+	if existing := findComponentStatus(stack.Status.Components, c.name); existing != nil {
+		status.Conditions = existing.Conditions
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: stack.Namespace, Name: c.deployment}, deployment); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return status, fmt.Errorf("failed to get %s deployment: %w", c.deployment, err)
+		}
+
+		// The components are rolled out one after another, a deployment which doesn't exist yet waits for the
+		// components before it.
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionAvailable, false, "Pending", "deployment is not created yet")
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionProgressing, true, "Pending", "deployment is not created yet")
+		setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionDegraded, false, "Pending", "deployment is not created yet")
+
+		return status, nil
+	}
+
+	if containers := deployment.Spec.Template.Spec.Containers; len(containers) > 0 {
+		status.Image = containers[0].Image
+	}
+
+	available, availableReason, availableMessage := deploymentAvailable(deployment)
+	setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionAvailable, available, availableReason, availableMessage)
+
+	degraded, degradedReason, degradedMessage := deploymentDegraded(deployment)
+	if revision, ok := deployment.Annotations[rolledBackToAnnotation]; ok {
+		degraded, degradedReason = true, "RolledBack"
+		degradedMessage = fmt.Sprintf("rollout failed, rolled back to revision %s", revision)
+	}
+	setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionDegraded, degraded, degradedReason, degradedMessage)
+
+	progressing, progressingReason, progressingMessage := deploymentProgressing(deployment)
+	setCondition(&status.Conditions, stack.Generation, v1alpha2.ConditionProgressing, progressing && !degraded, progressingReason, progressingMessage)
+
+	return status, nil
+}
+
+func deploymentAvailable(deployment *appsv1.Deployment) (bool, string, string) {
+	cond := deploymentCondition(deployment, appsv1.DeploymentAvailable)
+	if cond == nil {
+		return false, "Unknown", "deployment has not reported availability yet"
+	}
+
+	return cond.Status == corev1.ConditionTrue, cond.Reason, cond.Message
+}
+
+func deploymentProgressing(deployment *appsv1.Deployment) (bool, string, string) {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	switch {
+	case deployment.Status.ObservedGeneration < deployment.Generation:
+		return true, "RolloutPending", "deployment spec change has not been observed yet"
+	case deployment.Status.UpdatedReplicas < replicas:
+		return true, "RollingOut", fmt.Sprintf("%d/%d replicas updated", deployment.Status.UpdatedReplicas, replicas)
+	case deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		return true, "RollingOut", fmt.Sprintf("%d old replicas pending termination", deployment.Status.Replicas-deployment.Status.UpdatedReplicas)
+	case deployment.Status.AvailableReplicas < deployment.Status.UpdatedReplicas:
+		return true, "RollingOut", fmt.Sprintf("%d/%d updated replicas available", deployment.Status.AvailableReplicas, deployment.Status.UpdatedReplicas)
+	}
+
+	return false, "RolloutComplete", "deployment rollout is complete"
+}
+
+func deploymentDegraded(deployment *appsv1.Deployment) (bool, string, string) {
+	if cond := deploymentCondition(deployment, appsv1.DeploymentReplicaFailure); cond != nil && cond.Status == corev1.ConditionTrue {
+		return true, cond.Reason, cond.Message
+	}
+
+	if cond := deploymentCondition(deployment, appsv1.DeploymentProgressing); cond != nil &&
+		cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded" {
+		return true, cond.Reason, cond.Message
+	}
+
+	return false, "AsExpected", "deployment is not degraded"
+}
+
+func deploymentCondition(deployment *appsv1.Deployment, condType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == condType {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func findComponentStatus(components []v1alpha2.ComponentStatus, name string) *v1alpha2.ComponentStatus {
+	for i := range components {
+		if components[i].Name == name {
+			return &components[i]
+		}
+	}
+
+	return nil
+}
+
+func componentsMessage(state string, names []string) string {
+	if len(names) == 0 {
+		return fmt.Sprintf("no components %s", state)
+	}
+
+	return fmt.Sprintf("%s components: %s", state, strings.Join(names, ", "))
+}
+
+func setCondition(conditions *[]metav1.Condition, generation int64, condType string, status bool, reason, message string) {
+	condStatus := metav1.ConditionFalse
+	if status {
+		condStatus = metav1.ConditionTrue
+	}
+
+	// Reason is required for metav1.Condition, but deployment conditions are not guaranteed to carry one.
+	if reason == "" {
+		reason = "Unknown"
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               condType,
+		Status:             condStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -2,35 +2,62 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/tinkerbell/operator/api/v1alpha2"
 	"github.com/tinkerbell/operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
 
 	ctrlruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const (
 	ControllerName = "TinkerbellController"
+
+	cleanupRequeueInterval = 5 * time.Second
 )
 
-func Add(mgr manager.Manager, log *zap.SugaredLogger, clusterDNS, namespace string, workerCount int) error {
+func Add(mgr manager.Manager, log *zap.SugaredLogger, clusterDNS, clusterDNSService, resolvConf, clusterDomain, namespace, overwriteRegistry, dockerPullConfigJSONFile, hookDownloaderImage string, workerCount int) error {
+	dnsService := types.NamespacedName{}
+	if clusterDNSService != "" {
+		ns, name, ok := strings.Cut(clusterDNSService, "/")
+		if !ok || ns == "" || name == "" {
+			return fmt.Errorf("cluster DNS service %q is not in the namespace/name format", clusterDNSService)
+		}
+		dnsService = types.NamespacedName{Namespace: ns, Name: name}
+	}
+
 	reconciler := &Reconciler{
-		Client:     mgr.GetClient(),
-		log:        log,
-		namespace:  namespace,
-		clusterDNS: clusterDNS,
+		Client:                   mgr.GetClient(),
+		apiReader:                mgr.GetAPIReader(),
+		log:                      log,
+		namespace:                namespace,
+		clusterDNS:               clusterDNS,
+		clusterDNSService:        dnsService,
+		resolvConf:               resolvConf,
+		clusterDomain:            clusterDomain,
+		overwriteRegistry:        overwriteRegistry,
+		dockerPullConfigJSONFile: dockerPullConfigJSONFile,
+		hookDownloaderImage:      hookDownloaderImage,
 	}
 
 	c, err := controller.New(ControllerName, mgr, controller.Options{Reconciler: reconciler, MaxConcurrentReconciles: workerCount})
@@ -38,75 +65,303 @@ func Add(mgr manager.Manager, log *zap.SugaredLogger, clusterDNS, namespace stri
 		return err
 	}
 
+	if err := c.Watch(source.Kind(mgr.GetCache(), &v1alpha2.Stack{}), &handler.EnqueueRequestForObject{}, util.ByNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to create watch for %T: %w", &v1alpha2.Stack{}, err)
+	}
+
 	typesToWatch := []client.Object{
 		&corev1.Service{},
 		&corev1.ServiceAccount{},
+		&corev1.ConfigMap{},
+		&corev1.Secret{},
+		&corev1.PersistentVolumeClaim{},
 		&appsv1.Deployment{},
 		&rbacv1.Role{},
 		&rbacv1.RoleBinding{},
+	}
+
+	ownerHandler := handler.EnqueueRequestForOwner(mgr.GetScheme(), mgr.GetRESTMapper(), &v1alpha2.Stack{}, handler.OnlyControllerOwner())
+	for _, t := range typesToWatch {
+		if err := c.Watch(source.Kind(mgr.GetCache(), t), ownerHandler, util.ByNamespace(namespace)); err != nil {
+			return fmt.Errorf("failed to create watch for %T: %w", t, err)
+		}
+	}
+
+	// Cluster-scoped objects can't be owned by a namespaced Stack, they are mapped back to it through the stack labels.
+	clusterTypesToWatch := []client.Object{
 		&rbacv1.ClusterRole{},
 		&rbacv1.ClusterRoleBinding{},
 	}
 
-	for _, t := range typesToWatch {
-		if err := c.Watch(&source.Kind{Type: t}, &handler.EnqueueRequestForObject{}, util.ByNamespace(namespace)); err != nil {
+	labelHandler := handler.EnqueueRequestsFromMapFunc(enqueueStackByLabels)
+	for _, t := range clusterTypesToWatch {
+		if err := c.Watch(source.Kind(mgr.GetCache(), t), labelHandler); err != nil {
 			return fmt.Errorf("failed to create watch for %T: %w", t, err)
 		}
 	}
 
+	// The host ports of Smee are claimed by the oldest stack, the other stacks are checked again whenever a stack
+	// changes its spec or is deleted.
+	stackHandler := handler.EnqueueRequestsFromMapFunc(reconciler.enqueueStacks)
+	if err := c.Watch(source.Kind(mgr.GetCache(), &v1alpha2.Stack{}), stackHandler, predicate.GenerationChangedPredicate{}); err != nil {
+		return fmt.Errorf("failed to create watch for %T changes: %w", &v1alpha2.Stack{}, err)
+	}
+
+	// Nodes feed the trusted proxies and the discovered public address of every stack.
+	nodeHandler := handler.EnqueueRequestsFromMapFunc(reconciler.enqueueStacks)
+	if err := c.Watch(source.Kind(mgr.GetCache(), &corev1.Node{}), nodeHandler, util.NodeNetworkChanged()); err != nil {
+		return fmt.Errorf("failed to create watch for %T: %w", &corev1.Node{}, err)
+	}
+
+	// The public address is discovered from the node of the nginx proxy, a rescheduled proxy moves it.
+	nginxHandler := handler.EnqueueRequestsFromMapFunc(reconciler.enqueueNamespaceStacks)
+	if err := c.Watch(source.Kind(mgr.GetCache(), &corev1.Pod{}), nginxHandler, util.Factory(reconciler.isNginxPod), util.PodPlacementChanged()); err != nil {
+		return fmt.Errorf("failed to create watch for nginx pods: %w", err)
+	}
+
+	// The cluster DNS Service lives outside of the operator namespace, a change of its IP is propagated to every stack.
+	if dnsService.Name != "" {
+		dnsHandler := handler.EnqueueRequestsFromMapFunc(reconciler.enqueueStacks)
+		if err := c.Watch(source.Kind(mgr.GetCache(), &corev1.Service{}), dnsHandler, util.Factory(reconciler.isClusterDNSService)); err != nil {
+			return fmt.Errorf("failed to create watch for cluster DNS service: %w", err)
+		}
+	}
+
+	if dockerPullConfigJSONFile != "" {
+		watcher := &fileWatcher{
+			client:    mgr.GetClient(),
+			log:       log,
+			file:      dockerPullConfigJSONFile,
+			namespace: namespace,
+			events:    make(chan event.GenericEvent),
+		}
+
+		if err := mgr.Add(watcher); err != nil {
+			return fmt.Errorf("failed to add docker pull config file watcher: %w", err)
+		}
+
+		if err := c.Watch(&source.Channel{Source: watcher.events}, &handler.EnqueueRequestForObject{}); err != nil {
+			return fmt.Errorf("failed to create watch for docker pull config file: %w", err)
+		}
+	}
+
+	if err := addHookController(mgr, reconciler, workerCount); err != nil {
+		return fmt.Errorf("failed to add hook controller: %w", err)
+	}
+
 	return nil
 }
 
+// enqueueStackByLabels maps an object to the Stack referenced in its stack labels.
+func enqueueStackByLabels(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	name, namespace := labels[util.StackNameLabel], labels[util.StackNamespaceLabel]
+	if name == "" || namespace == "" {
+		return nil
+	}
+
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name, Namespace: namespace}}}
+}
+
+// enqueueStacks maps an object to all the Stacks the operator manages.
+func (r *Reconciler) enqueueStacks(ctx context.Context, _ client.Object) []reconcile.Request {
+	return r.listStackRequests(ctx, r.namespace)
+}
+
+// enqueueNamespaceStacks maps an object to the Stacks of its namespace.
+func (r *Reconciler) enqueueNamespaceStacks(ctx context.Context, obj client.Object) []reconcile.Request {
+	return r.listStackRequests(ctx, obj.GetNamespace())
+}
+
+// listStackRequests returns a request for each Stack of the given namespace, or of all namespaces if it is empty.
+func (r *Reconciler) listStackRequests(ctx context.Context, namespace string) []reconcile.Request {
+	stacks := &v1alpha2.StackList{}
+	if err := r.List(ctx, stacks, client.InNamespace(namespace)); err != nil {
+		r.log.Errorf("failed to list stacks: %v", err)
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(stacks.Items))
+	for _, stack := range stacks.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: stack.Name, Namespace: stack.Namespace}})
+	}
+
+	return requests
+}
+
 type Reconciler struct {
 	client.Client
-	log *zap.SugaredLogger
+	apiReader client.Reader
+	log       *zap.SugaredLogger
 
-	namespace  string
-	clusterDNS string
+	namespace                string
+	clusterDNS               string
+	clusterDNSService        types.NamespacedName
+	resolvConf               string
+	clusterDomain            string
+	overwriteRegistry        string
+	dockerPullConfigJSONFile string
+	hookDownloaderImage      string
 }
 
 func (r *Reconciler) Reconcile(ctx context.Context, req ctrlruntime.Request) (reconcile.Result, error) {
-	r.log.Info("Reconciling tinkerbell resources..")
+	r.log.Infof("Reconciling tinkerbell stack %q..", req.NamespacedName)
+
+	stack := &v1alpha2.Stack{}
+	if err := r.Get(ctx, req.NamespacedName, stack); err != nil {
+		if kerrors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+
+		return reconcile.Result{}, fmt.Errorf("failed to get stack %q: %w", req.NamespacedName, err)
+	}
 
-	if err := r.reconcile(ctx); err != nil {
-		r.log.Errorf("failed to reconcile %q due to: %v", req.Name, err)
+	if !stack.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, stack)
+	}
+
+	if controllerutil.AddFinalizer(stack, StackFinalizer) {
+		if err := r.Update(ctx, stack); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to add finalizer to stack %q: %w", req.NamespacedName, err)
+		}
+	}
+
+	// The reconciliation records discovered values in the stack status, so the status patch is computed against the
+	// stack as it was before reconciling.
+	original := stack.DeepCopy()
+
+	reconcileErr := r.reconcile(ctx, stack)
+
+	var pending *pendingError
+	waiting := errors.As(reconcileErr, &pending)
+	switch {
+	case waiting:
+		r.log.Infof("Tinkerbell stack %q is %s", req.NamespacedName, pending.message)
+	case reconcileErr != nil:
+		r.log.Errorf("failed to reconcile %q due to: %v", req.Name, reconcileErr)
+	}
+
+	if err := r.updateStatus(ctx, original, stack, reconcileErr); err != nil {
+		r.log.Errorf("failed to update status of %q due to: %v", req.Name, err)
+		if reconcileErr == nil || waiting {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if waiting {
+		return reconcile.Result{RequeueAfter: pendingRequeueInterval}, nil
+	}
+
+	return reconcile.Result{}, reconcileErr
+}
+
+func (r *Reconciler) finalize(ctx context.Context, stack *v1alpha2.Stack) (reconcile.Result, error) {
+	if !controllerutil.ContainsFinalizer(stack, StackFinalizer) {
+		return reconcile.Result{}, nil
+	}
+
+	r.log.Infof("Cleaning up tinkerbell stack %s/%s..", stack.Namespace, stack.Name)
+
+	done, err := r.cleanup(ctx, stack)
+	if err != nil {
+		r.log.Errorf("failed to clean up %q due to: %v", stack.Name, err)
 		return reconcile.Result{}, err
 	}
 
+	if !done {
+		return reconcile.Result{RequeueAfter: cleanupRequeueInterval}, nil
+	}
+
+	controllerutil.RemoveFinalizer(stack, StackFinalizer)
+	if err := r.Update(ctx, stack); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to remove finalizer from stack %q: %w", stack.Name, err)
+	}
+
 	return reconcile.Result{}, nil
 }
 
-func (r *Reconciler) reconcile(ctx context.Context) error {
-	if err := r.ensureTinkerbellServiceAccounts(ctx); err != nil {
+func (r *Reconciler) reconcile(ctx context.Context, stack *v1alpha2.Stack) error {
+	if err := r.ensureAirgapped(stack); err != nil {
+		return err
+	}
+
+	cfg, err := r.config(ctx, stack)
+	if err != nil {
+		return fmt.Errorf("failed to build stack configuration: %v", err)
+	}
+
+	stack.Status.PublicIP = cfg.PublicIP
+	stack.Status.ClusterDNS = cfg.ClusterDNS
+
+	if err := r.ensureTinkerbellCRDs(ctx, stack, cfg); err != nil {
+		var pending *pendingError
+		if errors.As(err, &pending) {
+			return err
+		}
+
+		return fmt.Errorf("failed to ensure tinkerbell crds: %v", err)
+	}
+
+	// A host port conflict only holds back Smee, the rest of the stack is still deployed.
+	hostPortsErr := r.ensureHostPorts(ctx, stack)
+
+	if err := r.ensureTinkerbellServiceAccounts(ctx, stack, cfg); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell service accounts: %v", err)
 	}
 
-	if err := r.ensureTinkerbellClusterRole(ctx); err != nil {
+	if err := r.ensureTinkerbellClusterRole(ctx, stack); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell cluster role: %v", err)
 	}
 
-	if err := r.ensureTinkerbellClusterRoleBinding(ctx); err != nil {
+	if err := r.ensureTinkerbellClusterRoleBinding(ctx, stack); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell cluster role bindings: %v", err)
 	}
 
-	if err := r.ensureTinkerbellRole(ctx); err != nil {
+	if err := r.ensureLegacyClusterRBACRemoved(ctx, stack); err != nil {
+		return fmt.Errorf("failed to remove legacy tinkerbell cluster rbac: %v", err)
+	}
+
+	if err := r.ensureTinkerbellRole(ctx, stack); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell role: %v", err)
 	}
 
-	if err := r.ensureTinkerbellRoleBinding(ctx); err != nil {
+	if err := r.ensureTinkerbellRoleBinding(ctx, stack); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell role binding: %v", err)
 	}
 
-	if err := r.ensureTinkerbellServices(ctx); err != nil {
+	if err := r.ensureTinkerbellServices(ctx, stack); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell services: %v", err)
 	}
 
-	if err := r.ensureTinkerbellConfigMaps(ctx); err != nil {
+	if err := r.ensureTinkerbellConfigMaps(ctx, stack, cfg); err != nil {
 		return fmt.Errorf("failed to ensure tinkerbell stack configmaps: %v", err)
 	}
 
-	if err := r.ensureTinkerbellDeployments(ctx); err != nil {
+	if err := r.ensureHookStorage(ctx, stack, cfg); err != nil {
+		return fmt.Errorf("failed to ensure hook artifacts storage: %v", err)
+	}
+
+	// Disabled components are removed first, a rollout waiting for the enabled components must not keep them running.
+	if err := r.ensureDisabledComponentsRemoved(ctx, stack); err != nil {
+		return fmt.Errorf("failed to ensure disabled components are removed: %v", err)
+	}
+
+	if err := r.ensureTinkerbellDeployments(ctx, stack, cfg, hostPortsErr == nil); err != nil {
+		var pending *pendingError
+		if errors.As(err, &pending) {
+			return err
+		}
+
 		return fmt.Errorf("failed to ensure tinkerbell deployments: %v", err)
 	}
+
+	if hostPortsErr != nil {
+		return hostPortsErr
+	}
+
+	if cfg.PublicIP == "" {
+		return errPublicAddressPending
+	}
+
 	return nil
 }
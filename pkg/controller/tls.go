@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/pki"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// errCertificatePending is returned while cert-manager hasn't issued the tink-server serving certificate yet.
+var errCertificatePending = errors.New("waiting for cert-manager to issue the tink-server certificate")
+
+// ensureTinkServerTLS makes sure a valid serving certificate for tink-server exists if TLS is enabled. The operator
+// issues the certificate itself unless cert-manager is configured and installed. Certificates are renewed during the
+// reconciliation, which happens at least every manager sync period, before they expire.
+func (r *Reconciler) ensureTinkServerTLS(ctx context.Context, stack *v1alpha2.Stack, cfg resources.Config) (*resources.TLS, error) {
+	tinkServer := stack.Spec.Services.TinkServer
+	if tinkServer == nil || !tinkServer.EnableTLS {
+		return nil, nil
+	}
+
+	dnsNames := tink.TinkServerDNSNames(cfg)
+
+	var ips []net.IP
+	if ip := net.ParseIP(cfg.PublicIP); ip != nil {
+		ips = append(ips, ip)
+	}
+
+	if tinkServer.CertManager != nil {
+		installed, err := r.certManagerInstalled()
+		if err != nil {
+			return nil, err
+		}
+
+		if installed {
+			return r.ensureCertManagerCertificate(ctx, stack, dnsNames, ips, tinkServer.CertManager.IssuerRef)
+		}
+
+		r.log.Warnf("cert-manager is not installed, issuing the tink-server certificate of %s/%s with the operator", stack.Namespace, stack.Name)
+	}
+
+	return r.ensureOperatorCertificate(ctx, stack, dnsNames, ips)
+}
+
+func (r *Reconciler) certManagerInstalled() (bool, error) {
+	_, err := r.RESTMapper().RESTMapping(tink.CertificateGVK.GroupKind(), tink.CertificateGVK.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to look up the cert-manager Certificate CRD: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *Reconciler) ensureCertManagerCertificate(ctx context.Context, stack *v1alpha2.Stack, dnsNames []string, ips []net.IP, issuer v1alpha2.CertManagerIssuerRef) (*resources.TLS, error) {
+	ipStrings := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ipStrings = append(ipStrings, ip.String())
+	}
+
+	if err := tink.CreateTinkServerCertificate(ctx, r.Client, stack, stack.Namespace, dnsNames, ipStrings, issuer); err != nil {
+		return nil, fmt.Errorf("failed to create tink-server certificate: %w", err)
+	}
+
+	secret, err := r.getSecret(ctx, stack.Namespace, tink.TinkServerTLSSecretName)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret == nil || len(secret.Data[corev1.TLSCertKey]) == 0 {
+		return nil, errCertificatePending
+	}
+
+	return tlsConfig(secret.Data[corev1.TLSCertKey], secret.Data[tink.CACertKey]), nil
+}
+
+func (r *Reconciler) ensureOperatorCertificate(ctx context.Context, stack *v1alpha2.Stack, dnsNames []string, ips []net.IP) (*resources.TLS, error) {
+	now := time.Now()
+
+	ca := pki.KeyPair{}
+	caSecret, err := r.getSecret(ctx, stack.Namespace, tink.TinkServerCASecretName)
+	if err != nil {
+		return nil, err
+	}
+	if caSecret != nil {
+		ca = pki.KeyPair{Cert: caSecret.Data[tink.CACertKey], Key: caSecret.Data[tink.CAKeyKey]}
+	}
+
+	if !pki.ValidCA(ca, now) {
+		r.log.Infof("Generating tink-server CA of %s/%s..", stack.Namespace, stack.Name)
+
+		if ca, err = pki.NewCA(fmt.Sprintf("tinkerbell-%s-ca", stack.Name)); err != nil {
+			return nil, fmt.Errorf("failed to generate tink-server CA: %w", err)
+		}
+	}
+
+	if err := tink.CreateTinkServerCASecret(ctx, r.Client, stack, stack.Namespace, ca); err != nil {
+		return nil, fmt.Errorf("failed to create tink-server CA secret: %w", err)
+	}
+
+	serving := pki.KeyPair{}
+	servingSecret, err := r.getSecret(ctx, stack.Namespace, tink.TinkServerTLSSecretName)
+	if err != nil {
+		return nil, err
+	}
+	if servingSecret != nil {
+		serving = pki.KeyPair{Cert: servingSecret.Data[corev1.TLSCertKey], Key: servingSecret.Data[corev1.TLSPrivateKeyKey]}
+	}
+
+	if !pki.ValidServingCert(serving, ca, dnsNames, ips, now) {
+		r.log.Infof("Generating tink-server serving certificate of %s/%s..", stack.Namespace, stack.Name)
+
+		if serving, err = pki.NewServingCert(ca, tink.TinkServerDeploymentName, dnsNames, ips); err != nil {
+			return nil, fmt.Errorf("failed to generate tink-server serving certificate: %w", err)
+		}
+	}
+
+	if err := tink.CreateTinkServerTLSSecret(ctx, r.Client, stack, stack.Namespace, serving, ca.Cert); err != nil {
+		return nil, fmt.Errorf("failed to create tink-server TLS secret: %w", err)
+	}
+
+	return tlsConfig(serving.Cert, ca.Cert), nil
+}
+
+func (r *Reconciler) getSecret(ctx context.Context, namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to get secret %q: %w", name, err)
+	}
+
+	return secret, nil
+}
+
+func tlsConfig(cert, caBundle []byte) *resources.TLS {
+	return &resources.TLS{
+		SecretName: tink.TinkServerTLSSecretName,
+		CABundle:   caBundle,
+		Checksum:   util.Checksum(cert),
+	}
+}
@@ -0,0 +1,214 @@
+// Package hook downloads, verifies and publishes the Hook artifacts the provisioned machines boot.
+package hook
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Archive is a Hook release archive and the artifacts it contains.
+type Archive struct {
+	// URL is the URL of the tar.gz archive.
+	URL string `json:"url"`
+	// Artifacts are the artifacts published from the archive.
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Artifact is a single file of a Hook archive, e.g. a kernel or an initramfs.
+type Artifact struct {
+	// Name is the file name of the artifact in the archive and in the published directory.
+	Name string `json:"name"`
+	// SHA512 is the expected sha512 checksum of the artifact.
+	SHA512 string `json:"sha512"`
+}
+
+// Publisher downloads Hook archives and publishes their verified artifacts into a directory.
+type Publisher struct {
+	// Dir is the directory the artifacts are published into.
+	Dir string
+	// Client is the HTTP client the archives are downloaded with.
+	Client *http.Client
+	// Logf logs the progress of the publishing.
+	Logf func(format string, args ...interface{})
+}
+
+// Publish publishes the artifacts of all the given archives. Archives whose artifacts already exist with the expected
+// checksums are not downloaded again. Artifacts are verified before they are renamed into place, so the directory
+// never contains partial or corrupted artifacts.
+func (p *Publisher) Publish(ctx context.Context, archives []Archive) error {
+	for _, archive := range archives {
+		if p.published(archive) {
+			p.Logf("artifacts of %s are already published", archive.URL)
+			continue
+		}
+
+		p.Logf("downloading %s", archive.URL)
+		if err := p.publish(ctx, archive); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", archive.URL, err)
+		}
+		p.Logf("published artifacts of %s", archive.URL)
+	}
+
+	return nil
+}
+
+func (p *Publisher) published(archive Archive) bool {
+	for _, artifact := range archive.Artifacts {
+		sum, err := fileChecksum(filepath.Join(p.Dir, artifact.Name))
+		if err != nil || sum != artifact.SHA512 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// open opens the given archive URL. Besides HTTP URLs, file URLs of locally mounted archives are supported.
+func (p *Publisher) open(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme == "file" {
+		return os.Open(u.Path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func (p *Publisher) publish(ctx context.Context, archive Archive) error {
+	body, err := p.open(ctx, archive.URL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip archive: %w", err)
+	}
+	defer gz.Close()
+
+	wanted := make(map[string]Artifact, len(archive.Artifacts))
+	for _, artifact := range archive.Artifacts {
+		wanted[artifact.Name] = artifact
+	}
+
+	// Verified artifacts are kept in temporary files next to their destination and only renamed into place once the
+	// whole archive is verified.
+	staged := make(map[string]string, len(wanted))
+	defer func() {
+		for _, tmp := range staged {
+			os.Remove(tmp)
+		}
+	}()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		artifact, ok := wanted[path.Base(header.Name)]
+		if !ok || header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		tmp, err := p.stage(tr, artifact)
+		if err != nil {
+			return err
+		}
+		staged[artifact.Name] = tmp
+	}
+
+	for name := range wanted {
+		if _, ok := staged[name]; !ok {
+			return fmt.Errorf("artifact %s not found in archive", name)
+		}
+	}
+
+	for name, tmp := range staged {
+		if err := os.Rename(tmp, filepath.Join(p.Dir, name)); err != nil {
+			return fmt.Errorf("failed to publish %s: %w", name, err)
+		}
+		delete(staged, name)
+	}
+
+	return nil
+}
+
+// stage writes the artifact into a temporary file and verifies its checksum.
+func (p *Publisher) stage(r io.Reader, artifact Artifact) (string, error) {
+	tmp, err := os.CreateTemp(p.Dir, "."+artifact.Name+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	h := sha512.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write %s: %w", artifact.Name, err)
+	}
+
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != artifact.SHA512 {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", artifact.Name, artifact.SHA512, sum)
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to set permissions of %s: %w", artifact.Name, err)
+	}
+
+	return tmp.Name(), nil
+}
+
+func fileChecksum(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha512.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,214 @@
+package hook
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+var testFiles = map[string]string{
+	"hook/vmlinuz-x86_64":    "kernel",
+	"hook/initramfs-x86_64":  "initramfs",
+	"hook/unrelated-content": "unrelated",
+}
+
+func checksum(content string) string {
+	sum := sha512.Sum512([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func newArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// serveArchive serves the archive and counts the downloads.
+func serveArchive(t *testing.T, archive []byte) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hook.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+
+		atomic.AddInt32(&downloads, 1)
+		w.Write(archive)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &downloads
+}
+
+func newPublisher(t *testing.T, server *httptest.Server) *Publisher {
+	t.Helper()
+
+	return &Publisher{Dir: t.TempDir(), Client: server.Client(), Logf: t.Logf}
+}
+
+func publishedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestPublish(t *testing.T) {
+	server, downloads := serveArchive(t, newArchive(t, testFiles))
+	p := newPublisher(t, server)
+
+	archives := []Archive{{
+		URL: server.URL + "/hook.tar.gz",
+		Artifacts: []Artifact{
+			{Name: "vmlinuz-x86_64", SHA512: checksum("kernel")},
+			{Name: "initramfs-x86_64", SHA512: checksum("initramfs")},
+		},
+	}}
+
+	if err := p.Publish(context.Background(), archives); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	if files, expected := publishedFiles(t, p.Dir), []string{"initramfs-x86_64", "vmlinuz-x86_64"}; strings.Join(files, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the published files %v, got %v", expected, files)
+	}
+
+	for name, content := range map[string]string{"vmlinuz-x86_64": "kernel", "initramfs-x86_64": "initramfs"} {
+		data, err := os.ReadFile(filepath.Join(p.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != content {
+			t.Errorf("expected %s to contain %q, got %q", name, content, data)
+		}
+	}
+
+	// Already published artifacts aren't downloaded again.
+	if err := p.Publish(context.Background(), archives); err != nil {
+		t.Fatalf("failed to publish again: %v", err)
+	}
+
+	if n := atomic.LoadInt32(downloads); n != 1 {
+		t.Errorf("expected the archive to be downloaded once, got %d downloads", n)
+	}
+}
+
+func TestPublishFileURL(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "hook.tar.gz")
+	if err := os.WriteFile(archivePath, newArchive(t, testFiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Publisher{Dir: t.TempDir(), Client: http.DefaultClient, Logf: t.Logf}
+	archives := []Archive{{
+		URL:       "file://" + archivePath,
+		Artifacts: []Artifact{{Name: "vmlinuz-x86_64", SHA512: checksum("kernel")}},
+	}}
+
+	if err := p.Publish(context.Background(), archives); err != nil {
+		t.Fatalf("failed to publish: %v", err)
+	}
+
+	if files := publishedFiles(t, p.Dir); len(files) != 1 || files[0] != "vmlinuz-x86_64" {
+		t.Errorf("expected only vmlinuz-x86_64 to be published, got %v", files)
+	}
+}
+
+func TestPublishErrors(t *testing.T) {
+	server, _ := serveArchive(t, newArchive(t, testFiles))
+
+	tests := map[string]struct {
+		archive Archive
+		err     string
+	}{
+		"checksum mismatch": {
+			archive: Archive{
+				URL: server.URL + "/hook.tar.gz",
+				Artifacts: []Artifact{
+					{Name: "vmlinuz-x86_64", SHA512: checksum("kernel")},
+					{Name: "initramfs-x86_64", SHA512: checksum("corrupted")},
+				},
+			},
+			err: "checksum mismatch for initramfs-x86_64",
+		},
+		"missing artifact": {
+			archive: Archive{
+				URL: server.URL + "/hook.tar.gz",
+				Artifacts: []Artifact{
+					{Name: "vmlinuz-x86_64", SHA512: checksum("kernel")},
+					{Name: "vmlinuz-aarch64", SHA512: checksum("kernel")},
+				},
+			},
+			err: "artifact vmlinuz-aarch64 not found in archive",
+		},
+		"missing archive": {
+			archive: Archive{
+				URL:       server.URL + "/missing.tar.gz",
+				Artifacts: []Artifact{{Name: "vmlinuz-x86_64", SHA512: checksum("kernel")}},
+			},
+			err: "unexpected status 404 Not Found",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := newPublisher(t, server)
+
+			err := p.Publish(context.Background(), []Archive{test.archive})
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Fatalf("expected an error containing %q, got %v", test.err, err)
+			}
+
+			// Nothing is published, not even the verified artifacts or temporary files, if the archive is rejected.
+			if files := publishedFiles(t, p.Dir); len(files) != 0 {
+				t.Errorf("expected no published files, got %v", files)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+)
+
+// DownloadCommand is the subcommand of the operator binary which publishes the Hook artifacts.
+const DownloadCommand = "hook-download"
+
+const (
+	// SourceDir is the directory the PersistentVolumeClaim source of the Hook archives is mounted at in the downloader.
+	SourceDir = "/hook-source"
+
+	// defaultURL is the base URL of the Hook GitHub releases.
+	defaultURL = "https://github.com/tinkerbell/hook/releases/download/%s"
+	// defaultImageSourcePath is the directory of the Hook archives in a source image.
+	defaultImageSourcePath = "/hook"
+)
+
+// DefaultArchitectures are the architectures Hook artifacts are published for by default.
+var DefaultArchitectures = []string{"x86_64", "aarch64"}
+
+// Archives returns the Hook version and the archives to publish for the given spec. Unset fields default to the Hook
+// release of the Stack version.
+func Archives(spec *v1alpha2.HookArtifacts, rel release.Hook) (string, []Archive, error) {
+	if spec == nil {
+		spec = &v1alpha2.HookArtifacts{}
+	}
+
+	version := rel.Version
+	if spec.Version != "" {
+		version = spec.Version
+	}
+
+	architectures := DefaultArchitectures
+	if len(spec.Architectures) > 0 {
+		architectures = spec.Architectures
+	}
+
+	baseURL := fmt.Sprintf(defaultURL, version)
+	if spec.URL != "" {
+		baseURL = spec.URL
+	}
+
+	if spec.Source != nil {
+		sourcePath, err := SourcePath(spec.Source)
+		if err != nil {
+			return "", nil, err
+		}
+		// The downloader runs from the source image, which contains the archives at their path, while a claim is mounted
+		// under the source directory.
+		if spec.Source.PersistentVolumeClaim != nil {
+			sourcePath = path.Join(SourceDir, sourcePath)
+		}
+		baseURL = "file://" + path.Join("/", sourcePath)
+	}
+
+	checksums := spec.Checksums
+	if len(checksums) == 0 && version == rel.Version {
+		checksums = rel.Checksums
+	}
+
+	archives := make([]Archive, 0, len(architectures))
+	for _, arch := range architectures {
+		archive := Archive{URL: fmt.Sprintf("%s/hook_%s.tar.gz", strings.TrimSuffix(baseURL, "/"), arch)}
+
+		for _, name := range []string{"vmlinuz-" + arch, "initramfs-" + arch} {
+			sum, ok := checksums[name]
+			if !ok {
+				return "", nil, fmt.Errorf("no checksum for hook artifact %s of version %s", name, version)
+			}
+
+			archive.Artifacts = append(archive.Artifacts, Artifact{Name: name, SHA512: sum})
+		}
+
+		archives = append(archives, archive)
+	}
+
+	return version, archives, nil
+}
+
+// SourcePath validates the given local source and returns the directory of the archives in it.
+func SourcePath(source *v1alpha2.HookArtifactsSource) (string, error) {
+	switch {
+	case source.Image != nil && source.PersistentVolumeClaim != nil:
+		return "", errors.New("only one of the image and persistentVolumeClaim hook sources can be set")
+	case source.Image != nil:
+		if source.Image.Image == "" {
+			return "", errors.New("the image of the hook image source is required")
+		}
+		if source.Image.Path == "" {
+			return defaultImageSourcePath, nil
+		}
+
+		return source.Image.Path, nil
+	case source.PersistentVolumeClaim != nil:
+		if source.PersistentVolumeClaim.ClaimName == "" {
+			return "", errors.New("the claim name of the hook persistentVolumeClaim source is required")
+		}
+
+		return source.PersistentVolumeClaim.Path, nil
+	default:
+		return "", errors.New("one of the image and persistentVolumeClaim hook sources must be set")
+	}
+}
+
+// ArtifactNames returns the file names of all the artifacts of the given archives.
+func ArtifactNames(archives []Archive) []string {
+	var names []string
+	for _, archive := range archives {
+		for _, artifact := range archive.Artifacts {
+			names = append(names, artifact.Name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,127 @@
+package hook
+
+import (
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+func TestArchives(t *testing.T) {
+	rel := release.Hook{
+		Version: "v0.8.0",
+		Checksums: map[string]string{
+			"vmlinuz-x86_64":    "kernel",
+			"initramfs-x86_64":  "initramfs",
+			"vmlinuz-aarch64":   "kernel-arm",
+			"initramfs-aarch64": "initramfs-arm",
+		},
+	}
+	x86Artifacts := []Artifact{{Name: "vmlinuz-x86_64", SHA512: "kernel"}, {Name: "initramfs-x86_64", SHA512: "initramfs"}}
+	x86Only := []string{"x86_64"}
+
+	tests := map[string]struct {
+		spec            *v1alpha2.HookArtifacts
+		expectedVersion string
+		expected        []Archive
+		expectErr       bool
+	}{
+		"release defaults": {
+			expectedVersion: "v0.8.0",
+			expected: []Archive{
+				{URL: "https://github.com/tinkerbell/hook/releases/download/v0.8.0/hook_x86_64.tar.gz", Artifacts: x86Artifacts},
+				{
+					URL:       "https://github.com/tinkerbell/hook/releases/download/v0.8.0/hook_aarch64.tar.gz",
+					Artifacts: []Artifact{{Name: "vmlinuz-aarch64", SHA512: "kernel-arm"}, {Name: "initramfs-aarch64", SHA512: "initramfs-arm"}},
+				},
+			},
+		},
+		"custom URL": {
+			spec:            &v1alpha2.HookArtifacts{URL: "http://artifacts.local/hook/", Architectures: x86Only},
+			expectedVersion: "v0.8.0",
+			expected:        []Archive{{URL: "http://artifacts.local/hook/hook_x86_64.tar.gz", Artifacts: x86Artifacts}},
+		},
+		"image source": {
+			spec: &v1alpha2.HookArtifacts{
+				Architectures: x86Only,
+				Source:        &v1alpha2.HookArtifactsSource{Image: &v1alpha2.HookImageSource{Image: "registry.local/hook-bundle:v0.8.0"}},
+			},
+			expectedVersion: "v0.8.0",
+			expected:        []Archive{{URL: "file:///hook/hook_x86_64.tar.gz", Artifacts: x86Artifacts}},
+		},
+		"image source with a path": {
+			spec: &v1alpha2.HookArtifacts{
+				Architectures: x86Only,
+				Source: &v1alpha2.HookArtifactsSource{
+					Image: &v1alpha2.HookImageSource{Image: "registry.local/hook-bundle:v0.8.0", Path: "/srv/hook/"},
+				},
+			},
+			expectedVersion: "v0.8.0",
+			expected:        []Archive{{URL: "file:///srv/hook/hook_x86_64.tar.gz", Artifacts: x86Artifacts}},
+		},
+		"claim source": {
+			spec: &v1alpha2.HookArtifacts{
+				Architectures: x86Only,
+				Source:        &v1alpha2.HookArtifactsSource{PersistentVolumeClaim: &v1alpha2.HookVolumeSource{ClaimName: "hook"}},
+			},
+			expectedVersion: "v0.8.0",
+			expected:        []Archive{{URL: "file://" + SourceDir + "/hook_x86_64.tar.gz", Artifacts: x86Artifacts}},
+		},
+		"claim source with a path": {
+			spec: &v1alpha2.HookArtifacts{
+				Architectures: x86Only,
+				Source: &v1alpha2.HookArtifactsSource{
+					PersistentVolumeClaim: &v1alpha2.HookVolumeSource{ClaimName: "hook", Path: "releases/v0.8.0"},
+				},
+			},
+			expectedVersion: "v0.8.0",
+			expected:        []Archive{{URL: "file://" + SourceDir + "/releases/v0.8.0/hook_x86_64.tar.gz", Artifacts: x86Artifacts}},
+		},
+		"source without image": {
+			spec:      &v1alpha2.HookArtifacts{Source: &v1alpha2.HookArtifactsSource{Image: &v1alpha2.HookImageSource{}}},
+			expectErr: true,
+		},
+		"custom version with checksums": {
+			spec: &v1alpha2.HookArtifacts{
+				Version:       "v0.8.1",
+				Architectures: x86Only,
+				Checksums:     map[string]string{"vmlinuz-x86_64": "kernel-1", "initramfs-x86_64": "initramfs-1"},
+			},
+			expectedVersion: "v0.8.1",
+			expected: []Archive{{
+				URL:       "https://github.com/tinkerbell/hook/releases/download/v0.8.1/hook_x86_64.tar.gz",
+				Artifacts: []Artifact{{Name: "vmlinuz-x86_64", SHA512: "kernel-1"}, {Name: "initramfs-x86_64", SHA512: "initramfs-1"}},
+			}},
+		},
+		"custom version without checksums": {
+			spec:      &v1alpha2.HookArtifacts{Version: "v0.8.1"},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			version, archives, err := Archives(test.spec, rel)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if version != test.expectedVersion {
+				t.Errorf("expected version %q, got %q", test.expectedVersion, version)
+			}
+
+			if !apiequality.Semantic.DeepEqual(archives, test.expected) {
+				t.Errorf("unexpected archives:\n%s", diff.ObjectReflectDiff(test.expected, archives))
+			}
+		})
+	}
+}
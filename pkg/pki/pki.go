@@ -0,0 +1,198 @@
+// Package pki generates and validates the certificates the operator manages for the stack services.
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+)
+
+const (
+	// CAValidity is the validity of the generated CA certificates.
+	CAValidity = 10 * 365 * 24 * time.Hour
+	// ServingValidity is the validity of the generated serving certificates.
+	ServingValidity = 365 * 24 * time.Hour
+	// RenewBefore is the time before expiry a certificate is renewed.
+	RenewBefore = 30 * 24 * time.Hour
+)
+
+// KeyPair is a PEM encoded certificate and its private key.
+type KeyPair struct {
+	Cert []byte
+	Key  []byte
+}
+
+// NewCA generates a self-signed CA certificate.
+func NewCA(commonName string) (KeyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return newKeyPair(template, template, key, key)
+}
+
+// NewServingCert generates a serving certificate for the given DNS names and IPs signed by the given CA.
+func NewServingCert(ca KeyPair, commonName string, dnsNames []string, ips []net.IP) (KeyPair, error) {
+	caCert, err := ParseCert(ca.Cert)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	caKey, err := parseKey(ca.Key)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: commonName},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(ServingValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+	}
+
+	return newKeyPair(template, caCert, key, caKey)
+}
+
+func newKeyPair(template, parent *x509.Certificate, key *ecdsa.PrivateKey, signer crypto.Signer) (KeyPair, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	template.SerialNumber = serial
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), signer)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("failed to marshal key: %w", err)
+	}
+
+	return KeyPair{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// ParseCert parses a PEM encoded certificate.
+func ParseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "EC PRIVATE KEY" {
+		return nil, errors.New("no PEM encoded EC private key found")
+	}
+
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// ValidCA returns whether the key pair is a valid CA which doesn't need to be renewed yet.
+func ValidCA(ca KeyPair, now time.Time) bool {
+	cert, err := ParseCert(ca.Cert)
+	if err != nil || !cert.IsCA {
+		return false
+	}
+
+	if _, err := parseKey(ca.Key); err != nil {
+		return false
+	}
+
+	return !needsRenewal(cert, now)
+}
+
+// ValidServingCert returns whether the key pair is signed by the given CA, covers exactly the given DNS names and IPs
+// and doesn't need to be renewed yet.
+func ValidServingCert(serving, ca KeyPair, dnsNames []string, ips []net.IP, now time.Time) bool {
+	cert, err := ParseCert(serving.Cert)
+	if err != nil {
+		return false
+	}
+
+	if _, err := parseKey(serving.Key); err != nil {
+		return false
+	}
+
+	caCert, err := ParseCert(ca.Cert)
+	if err != nil {
+		return false
+	}
+
+	if err := cert.CheckSignatureFrom(caCert); err != nil {
+		return false
+	}
+
+	return !needsRenewal(cert, now) && equalStrings(cert.DNSNames, dnsNames) && equalIPs(cert.IPAddresses, ips)
+}
+
+func needsRenewal(cert *x509.Certificate, now time.Time) bool {
+	return now.Add(RenewBefore).After(cert.NotAfter)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalIPs(a, b []net.IP) bool {
+	toStrings := func(ips []net.IP) []string {
+		s := make([]string, 0, len(ips))
+		for _, ip := range ips {
+			s = append(s, ip.String())
+		}
+		return s
+	}
+
+	return equalStrings(toStrings(a), toStrings(b))
+}
@@ -0,0 +1,292 @@
+package pki
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+)
+
+var (
+	testDNSNames = []string{"tink-server.tinkerbell.svc", "tink-server"}
+	testIPs      = []net.IP{net.ParseIP("192.168.1.10")}
+)
+
+func newTestCA(t *testing.T) KeyPair {
+	t.Helper()
+
+	ca, err := NewCA("test-ca")
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	return ca
+}
+
+func newTestServingCert(t *testing.T, ca KeyPair) KeyPair {
+	t.Helper()
+
+	serving, err := NewServingCert(ca, "tink-server", testDNSNames, testIPs)
+	if err != nil {
+		t.Fatalf("failed to generate serving certificate: %v", err)
+	}
+
+	return serving
+}
+
+func mustParseCert(t *testing.T, data []byte) *x509.Certificate {
+	t.Helper()
+
+	cert, err := ParseCert(data)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return cert
+}
+
+func TestNewCA(t *testing.T) {
+	before := time.Now()
+	ca := newTestCA(t)
+	cert := mustParseCert(t, ca.Cert)
+
+	if !cert.IsCA || !cert.BasicConstraintsValid {
+		t.Errorf("expected a CA certificate, got IsCA=%t BasicConstraintsValid=%t", cert.IsCA, cert.BasicConstraintsValid)
+	}
+
+	if cert.Subject.CommonName != "test-ca" {
+		t.Errorf("expected common name test-ca, got %q", cert.Subject.CommonName)
+	}
+
+	if cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Errorf("expected the cert sign key usage, got %v", cert.KeyUsage)
+	}
+
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Errorf("expected a self-signed certificate: %v", err)
+	}
+
+	if cert.NotBefore.After(before) {
+		t.Errorf("expected the certificate to be valid from before %s, got %s", before, cert.NotBefore)
+	}
+
+	if notAfter := before.Add(CAValidity); cert.NotAfter.Before(notAfter.Add(-time.Minute)) || cert.NotAfter.After(notAfter.Add(time.Minute)) {
+		t.Errorf("expected the certificate to expire at %s, got %s", notAfter, cert.NotAfter)
+	}
+}
+
+func TestNewServingCert(t *testing.T) {
+	before := time.Now()
+	ca := newTestCA(t)
+	serving := newTestServingCert(t, ca)
+	cert := mustParseCert(t, serving.Cert)
+
+	if cert.IsCA {
+		t.Error("expected a non-CA certificate")
+	}
+
+	if !equalStrings(cert.DNSNames, testDNSNames) {
+		t.Errorf("expected DNS names %v, got %v", testDNSNames, cert.DNSNames)
+	}
+
+	if !equalIPs(cert.IPAddresses, testIPs) {
+		t.Errorf("expected IPs %v, got %v", testIPs, cert.IPAddresses)
+	}
+
+	if cert.KeyUsage != x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment {
+		t.Errorf("expected the digital signature and key encipherment key usages, got %v", cert.KeyUsage)
+	}
+
+	if len(cert.ExtKeyUsage) != 1 || cert.ExtKeyUsage[0] != x509.ExtKeyUsageServerAuth {
+		t.Errorf("expected the server auth extended key usage, got %v", cert.ExtKeyUsage)
+	}
+
+	if notAfter := before.Add(ServingValidity); cert.NotAfter.Before(notAfter.Add(-time.Minute)) || cert.NotAfter.After(notAfter.Add(time.Minute)) {
+		t.Errorf("expected the certificate to expire at %s, got %s", notAfter, cert.NotAfter)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(mustParseCert(t, ca.Cert))
+
+	for _, name := range append([]string{"192.168.1.10"}, testDNSNames...) {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: name, Roots: roots}); err != nil {
+			t.Errorf("expected the certificate to verify for %s against the CA: %v", name, err)
+		}
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: cert.NotAfter.Add(time.Hour)}); err == nil {
+		t.Error("expected the certificate to be rejected after its expiry")
+	}
+
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(mustParseCert(t, newTestCA(t).Cert))
+
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: otherRoots}); err == nil {
+		t.Error("expected the certificate to be rejected by another CA")
+	}
+}
+
+func TestNewServingCertInvalidCA(t *testing.T) {
+	ca := newTestCA(t)
+
+	tests := map[string]KeyPair{
+		"invalid certificate": {Cert: []byte("invalid"), Key: ca.Key},
+		"invalid key":         {Cert: ca.Cert, Key: []byte("invalid")},
+	}
+
+	for name, ca := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewServingCert(ca, "tink-server", testDNSNames, testIPs); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestValidCA(t *testing.T) {
+	ca := newTestCA(t)
+	notAfter := mustParseCert(t, ca.Cert).NotAfter
+	serving := newTestServingCert(t, ca)
+
+	tests := map[string]struct {
+		ca    KeyPair
+		now   time.Time
+		valid bool
+	}{
+		"valid": {
+			ca:    ca,
+			now:   time.Now(),
+			valid: true,
+		},
+		"just outside the renewal threshold": {
+			ca:    ca,
+			now:   notAfter.Add(-RenewBefore - time.Minute),
+			valid: true,
+		},
+		"within the renewal threshold": {
+			ca:  ca,
+			now: notAfter.Add(-RenewBefore + time.Minute),
+		},
+		"expired": {
+			ca:  ca,
+			now: notAfter.Add(time.Hour),
+		},
+		"not a CA": {
+			ca:  serving,
+			now: time.Now(),
+		},
+		"invalid certificate": {
+			ca:  KeyPair{Cert: []byte("invalid"), Key: ca.Key},
+			now: time.Now(),
+		},
+		"invalid key": {
+			ca:  KeyPair{Cert: ca.Cert, Key: []byte("invalid")},
+			now: time.Now(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if valid := ValidCA(test.ca, test.now); valid != test.valid {
+				t.Errorf("expected ValidCA to return %t, got %t", test.valid, valid)
+			}
+		})
+	}
+}
+
+func TestValidServingCert(t *testing.T) {
+	ca := newTestCA(t)
+	serving := newTestServingCert(t, ca)
+	notAfter := mustParseCert(t, serving.Cert).NotAfter
+
+	tests := map[string]struct {
+		serving  KeyPair
+		ca       KeyPair
+		dnsNames []string
+		ips      []net.IP
+		now      time.Time
+		valid    bool
+	}{
+		"valid": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      time.Now(),
+			valid:    true,
+		},
+		"DNS names in another order": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: []string{testDNSNames[1], testDNSNames[0]},
+			ips:      testIPs,
+			now:      time.Now(),
+			valid:    true,
+		},
+		"just outside the renewal threshold": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      notAfter.Add(-RenewBefore - time.Minute),
+			valid:    true,
+		},
+		"within the renewal threshold": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      notAfter.Add(-RenewBefore + time.Minute),
+		},
+		"signed by another CA": {
+			serving:  serving,
+			ca:       newTestCA(t),
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      time.Now(),
+		},
+		"missing DNS name": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: append([]string{"tink-server.tinkerbell"}, testDNSNames...),
+			ips:      testIPs,
+			now:      time.Now(),
+		},
+		"extra DNS name": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: testDNSNames[:1],
+			ips:      testIPs,
+			now:      time.Now(),
+		},
+		"changed IP": {
+			serving:  serving,
+			ca:       ca,
+			dnsNames: testDNSNames,
+			ips:      []net.IP{net.ParseIP("192.168.1.11")},
+			now:      time.Now(),
+		},
+		"invalid key": {
+			serving:  KeyPair{Cert: serving.Cert, Key: []byte("invalid")},
+			ca:       ca,
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      time.Now(),
+		},
+		"invalid CA": {
+			serving:  serving,
+			ca:       KeyPair{Cert: []byte("invalid")},
+			dnsNames: testDNSNames,
+			ips:      testIPs,
+			now:      time.Now(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if valid := ValidServingCert(test.serving, test.ca, test.dnsNames, test.ips, test.now); valid != test.valid {
+				t.Errorf("expected ValidServingCert to return %t, got %t", test.valid, valid)
+			}
+		})
+	}
+}
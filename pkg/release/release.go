@@ -0,0 +1,136 @@
+package release
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed releases.yaml
+var releasesData []byte
+
+// crdsData contains the CRD manifests of every release in the crds/<version> directory.
+//
+//go:embed crds
+var crdsData embed.FS
+
+// Release contains the images of the stack components that are compatible with a Tinkerbell release.
+type Release struct {
+	// Version is the Tinkerbell release version.
+	Version string `json:"version"`
+	// Smee is the smee image of the release.
+	Smee v1alpha2.Image `json:"smee"`
+	// Hegel is the hegel image of the release.
+	Hegel v1alpha2.Image `json:"hegel"`
+	// Rufio is the rufio image of the release.
+	Rufio v1alpha2.Image `json:"rufio"`
+	// TinkServer is the tink server image of the release.
+	TinkServer v1alpha2.Image `json:"tinkServer"`
+	// TinkController is the tink controller image of the release.
+	TinkController v1alpha2.Image `json:"tinkController"`
+	// TinkWorker is the tink worker image the provisioned machines run.
+	TinkWorker v1alpha2.Image `json:"tinkWorker"`
+	// Hook contains the Hook artifacts the provisioned machines boot.
+	Hook Hook `json:"hook"`
+	// CRDs are the CRDs of the Tinkerbell services of the release. They are loaded from the crds/<version> directory.
+	CRDs []apiextensionsv1.CustomResourceDefinition `json:"-"`
+}
+
+// Hook contains the details of a Hook release.
+type Hook struct {
+	// Version is the Hook release version.
+	Version string `json:"version"`
+	// Checksums maps the Hook artifact file names to their sha512 checksums.
+	Checksums map[string]string `json:"checksums"`
+}
+
+type matrix struct {
+	Releases []Release `json:"releases"`
+}
+
+var releases = mustParse(releasesData)
+
+func mustParse(data []byte) map[string]Release {
+	m := matrix{}
+	if err := yaml.UnmarshalStrict(data, &m); err != nil {
+		panic(fmt.Sprintf("failed to parse release matrix: %v", err))
+	}
+
+	parsed := make(map[string]Release, len(m.Releases))
+	for _, r := range m.Releases {
+		crds, err := parseCRDs(crdsData, r.Version)
+		if err != nil {
+			panic(fmt.Sprintf("failed to parse CRDs of release %s: %v", r.Version, err))
+		}
+		r.CRDs = crds
+
+		parsed[r.Version] = r
+	}
+
+	return parsed
+}
+
+func parseCRDs(fsys fs.FS, version string) ([]apiextensionsv1.CustomResourceDefinition, error) {
+	dir := path.Join("crds", version)
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	crds := make([]apiextensionsv1.CustomResourceDefinition, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		crd := apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.UnmarshalStrict(data, &crd); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+		}
+
+		// The manifests are generated with an empty status, which is owned by the API server.
+		crd.Status = apiextensionsv1.CustomResourceDefinitionStatus{}
+		crds = append(crds, crd)
+	}
+
+	if len(crds) == 0 {
+		return nil, fmt.Errorf("no CRDs found in %s", dir)
+	}
+
+	return crds, nil
+}
+
+// Get returns the release of the given Tinkerbell version.
+func Get(version string) (Release, error) {
+	r, ok := releases[version]
+	if !ok {
+		return Release{}, fmt.Errorf("unknown tinkerbell version %q, supported versions are: %s", version, strings.Join(Versions(), ", "))
+	}
+
+	return r, nil
+}
+
+// Versions returns all the Tinkerbell versions in the release matrix.
+func Versions() []string {
+	versions := make([]string, 0, len(releases))
+	for v := range releases {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
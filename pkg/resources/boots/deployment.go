@@ -2,21 +2,63 @@ package boots
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ptr "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+const (
+	// ComponentName is the name of the smee component in the Stack.
+	ComponentName = "smee"
+	// DeploymentName is the name of the smee deployment.
+	DeploymentName = "boots"
+	// ServiceName is the name of the smee service.
+	ServiceName = "boots"
+
+	// DefaultLogLevel is the log level of smee if the Stack doesn't set one.
+	DefaultLogLevel = "debug"
+
+	// DefaultListenIP is the local IP smee listens on if the Stack doesn't set one.
+	DefaultListenIP = "0.0.0.0"
+	// DefaultDHCPPort is the port the smee DHCP server listens on if the Stack doesn't set one.
+	DefaultDHCPPort = 67
+	// DefaultHTTPPort is the port the smee iPXE HTTP server listens on if the Stack doesn't set one.
+	DefaultHTTPPort = 80
+	// DefaultSyslogPort is the port the smee syslog server listens on if the Stack doesn't set one.
+	DefaultSyslogPort = 514
+	// DefaultTFTPPort is the port the smee TFTP server listens on if the Stack doesn't set one.
+	DefaultTFTPPort = 69
+)
+
+func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	var image v1alpha2.Image
+	var settings v1alpha2.ComponentDeployment
+
+	smee := stack.Spec.Services.Smee
+	if smee != nil {
+		image = smee.Image
+		settings = smee.ComponentDeployment
+	}
+
+	tinkWorkerImage := util.Image(v1alpha2.Image{}, cfg.Release.TinkWorker.Repository, cfg.Release.TinkWorker.Tag, cfg.Registry)
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "boots",
-			Namespace: ns,
+			Name:      DeploymentName,
+			Namespace: cfg.Namespace,
 			Labels: map[string]string{
 				"app": "boots",
 			},
@@ -40,100 +82,266 @@ func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns s
 					Containers: []corev1.Container{
 						{
 							Name:            "boots",
-							Image:           "quay.io/tinkerbell/boots:v0.8.0",
+							Image:           util.Image(image, cfg.Release.Smee.Repository, cfg.Release.Smee.Tag, cfg.Registry),
 							ImagePullPolicy: corev1.PullIfNotPresent,
-							Args:            []string{"--dhcp-addr", "0.0.0.0:67", "--kube-namespace", ns},
-							Env:             parsedEnvVars(),
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
+							Args:            args(smee, cfg, tinkWorkerImage),
+							Env:             env(smee, cfg, resources.TinkServerPort(stack)),
+							Resources:       resources.ContainerResources(settings.Resources),
 						},
 					},
 					ServiceAccountName: serviceAccountName,
+					ImagePullSecrets:   cfg.ImagePullSecrets,
 					HostNetwork:        true,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
+	resources.ApplyComponentDeployment(deployment, settings)
+	util.SetStackLabels(deployment, stack, ComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, deployment)
+}
+
+// listenAddresses contains the local addresses smee listens on.
+type listenAddresses struct {
+	dhcp   address
+	http   address
+	syslog address
+	tftp   address
+}
+
+type address struct {
+	ip   string
+	port int
+}
+
+func (a address) String() string {
+	return net.JoinHostPort(a.ip, strconv.Itoa(a.port))
+}
+
+// Ports contains the ports smee listens on.
+type Ports struct {
+	DHCP   int
+	HTTP   int
+	Syslog int
+	TFTP   int
+}
+
+// ListenPorts returns the ports smee listens on, the nginx proxy forwards the same ports to smee.
+func ListenPorts(smee *v1alpha2.Smee) Ports {
+	addrs := addresses(smee)
+
+	return Ports{
+		DHCP:   addrs.dhcp.port,
+		HTTP:   addrs.http.port,
+		Syslog: addrs.syslog.port,
+		TFTP:   addrs.tftp.port,
+	}
+}
+
+// addresses returns the listen addresses of smee, falling back to the smee defaults for the unset configs.
+func addresses(smee *v1alpha2.Smee) listenAddresses {
+	addrs := listenAddresses{
+		dhcp:   address{ip: DefaultListenIP, port: DefaultDHCPPort},
+		http:   address{ip: DefaultListenIP, port: DefaultHTTPPort},
+		syslog: address{ip: DefaultListenIP, port: DefaultSyslogPort},
+		tftp:   address{ip: DefaultListenIP, port: DefaultTFTPPort},
+	}
+
+	if smee == nil {
+		return addrs
+	}
+
+	if c := smee.DHCPConfigs; c != nil {
+		addrs.dhcp = overrideAddress(addrs.dhcp, c.IP, c.Port)
+	}
+	if c := smee.IPXEConfigs; c != nil {
+		addrs.http = overrideAddress(addrs.http, c.IP, c.Port)
+	}
+	if c := smee.SyslogConfigs; c != nil {
+		addrs.syslog = overrideAddress(addrs.syslog, c.IP, c.Port)
+	}
+	if c := smee.TFTPConfigs; c != nil {
+		addrs.tftp = overrideAddress(addrs.tftp, c.IP, c.Port)
+	}
+
+	return addrs
+}
+
+func overrideAddress(addr address, ip string, port int) address {
+	if ip != "" {
+		addr.ip = ip
+	}
+	if port != 0 {
+		addr.port = port
+	}
+
+	return addr
+}
+
+// args translates the smee spec into the command line flags of boots v0.8.0, which the release matrix deploys as
+// smee. The settings boots only reads from the environment are rendered by env.
+func args(smee *v1alpha2.Smee, cfg resources.Config, tinkWorkerImage string) []string {
+	if smee == nil {
+		smee = &v1alpha2.Smee{}
+	}
+
+	addrs := addresses(smee)
+
+	logLevel := DefaultLogLevel
+	if smee.LogLevel != nil {
+		logLevel = *smee.LogLevel
+	}
+
+	args := []string{
+		"--log-level=" + logLevel,
+		"--dhcp-addr=" + addrs.dhcp.String(),
+		"--syslog-addr=" + addrs.syslog.String(),
+		"--ipxe-tftp-addr=" + addrs.tftp.String(),
+		"--http-addr=" + addrs.http.String(),
+	}
+
+	args = append(args, backendArgs(smee.BackendConfigs, cfg.Namespace)...)
+	args = append(args, tftpArgs(smee.TFTPConfigs)...)
+	args = append(args, ipxeArgs(smee.IPXEConfigs, cfg, tinkWorkerImage)...)
+
+	return append(args, dhcpArgs(smee.DHCPConfigs)...)
+}
+
+func backendArgs(backend v1alpha2.BackendConfigs, ns string) []string {
+	// The file backend is configured through the environment.
+	if backend.BackendFileMode != nil {
+		return nil
+	}
+
+	kubeNamespace := ns
+	var args []string
+
+	if kube := backend.BackendKubeMode; kube != nil {
+		if kube.KubeConfigFilePath != nil {
+			args = append(args, "--kubeconfig="+*kube.KubeConfigFilePath)
+		}
+		if kube.KubeAPIURL != nil {
+			args = append(args, "--kubernetes="+*kube.KubeAPIURL)
+		}
+		if kube.KubeNamespace != nil {
+			kubeNamespace = *kube.KubeNamespace
 		}
+	}
+
+	return append(args, "--kube-namespace="+kubeNamespace)
+}
 
-		return err
+func tftpArgs(tftp *v1alpha2.TFTPConfigs) []string {
+	if tftp == nil || tftp.TFTPTimeout == nil {
+		return nil
 	}
 
-	return nil
+	return []string{fmt.Sprintf("--ipxe-tftp-timeout=%ds", *tftp.TFTPTimeout)}
 }
 
-func parsedEnvVars() []corev1.EnvVar {
-	return []corev1.EnvVar{
-		{
-			Name: "TRUSTED_PROXIES",
-			// TODO: pass the TRUSTED_PROXIES as a command line
-			Value: "10.244.0.0/24,10.244.1.0/24,10.244.2.0/24",
-		},
-		{
-			Name:  "DATA_MODEL_VERSION",
-			Value: "kubernetes",
-		},
-		{
-			Name:  "FACILITY_CODE",
-			Value: "lab1",
-		},
-		{
-			Name:  "HTTP_BIND",
-			Value: ":80",
-		},
-		{
-			Name: "MIRROR_BASE_URL",
-			// TODO: pass the MIRROR_BASE_URL as a command line
-			// TODO: configure http(s) scheme for MIRROR_BASE_URL
-			Value: "http://10.10.15.153",
-		},
-		{
-			Name: "BOOTS_OSIE_PATH_OVERRIDE",
-			// TODO: pass the BOOTS_OSIE_PATH_OVERRIDE as a command line
-			Value: "10.10.15.153",
-		},
-		{
-			Name: "PUBLIC_IP",
-			// TODO: pass the PUBLIC_IP as a command line
-			Value: "10.10.15.153",
-		},
-		{
-			Name: "PUBLIC_SYSLOG_FQDN",
-			// TODO: pass the PUBLIC_SYSLOG_FQDN as a command line
-			Value: "10.10.15.153",
-		},
-		{
-			Name:  "SYSLOG_BIND",
-			Value: ":514",
-		},
-		{
-			Name: "TINKERBELL_GRPC_AUTHORITY",
-			// TODO: pass the TINKERBELL_GRPC_AUTHORITY as a command line
-			Value: "10.10.15.153",
-		},
-		{
-			Name:  "TINKERBELL_TLS",
-			Value: "false",
-		},
-		{
-			Name:  "BOOTS_LOG_LEVEL",
-			Value: "debug",
-		},
-		{
-			Name:  "BOOTS_EXTRA_KERNEL_ARGS",
-			Value: "tink_worker_image=quay.io/tinkerbell/tink-worker:v0.8.0",
-		},
+func ipxeArgs(ipxe *v1alpha2.IPXEConfigs, cfg resources.Config, tinkWorkerImage string) []string {
+	var (
+		enableBinary = true
+		kernelArgs   = []string{"tink_worker_image=" + tinkWorkerImage}
+	)
+
+	if ipxe != nil {
+		if ipxe.EnableHTTPBinary != nil {
+			enableBinary = *ipxe.EnableHTTPBinary
+		}
+		if ipxe.ExtraKernelArgs != nil && *ipxe.ExtraKernelArgs != "" {
+			kernelArgs = append(kernelArgs, *ipxe.ExtraKernelArgs)
+		}
+	}
+
+	// tink-worker verifies the tink-server certificate with the CA bundle passed on the kernel command line.
+	if tls := cfg.TinkServerTLS; tls != nil && len(tls.CABundle) > 0 {
+		kernelArgs = append(kernelArgs, "tinkerbell_tls_ca="+base64.StdEncoding.EncodeToString(tls.CABundle))
+	}
+
+	return []string{
+		"--ipxe-enable-http=" + strconv.FormatBool(enableBinary),
+		"--extra-kernel-args=" + strings.Join(kernelArgs, " "),
+	}
+}
+
+func dhcpArgs(dhcp *v1alpha2.DHCPConfigs) []string {
+	if dhcp == nil {
+		return nil
+	}
+
+	var args []string
+	if dhcp.TFTPAddress != nil {
+		args = append(args, "--ipxe-remote-tftp-addr="+*dhcp.TFTPAddress)
+	}
+	if dhcp.HTTPIPXEBinaryAddress != nil {
+		args = append(args, "--ipxe-remote-http-addr="+*dhcp.HTTPIPXEBinaryAddress)
+	}
+
+	return args
+}
+
+// env translates the smee spec into the environment variables boots v0.8.0 reads its remaining settings from.
+func env(smee *v1alpha2.Smee, cfg resources.Config, tinkServerPort int) []corev1.EnvVar {
+	if smee == nil {
+		smee = &v1alpha2.Smee{}
+	}
+
+	var (
+		publicIP       = cfg.PublicIP
+		syslogIP       = cfg.PublicIP
+		tinkServer     = net.JoinHostPort(cfg.PublicIP, strconv.Itoa(tinkServerPort))
+		hookURL        = "http://" + net.JoinHostPort(cfg.PublicIP, strconv.Itoa(resources.HookHTTPPort))
+		enableTLS      = cfg.TinkServerTLS != nil
+		trustedProxies = cfg.TrustedProxies
+	)
+
+	if dhcp := smee.DHCPConfigs; dhcp != nil {
+		if dhcp.IPForPacket != nil {
+			publicIP = *dhcp.IPForPacket
+		}
+		if dhcp.SyslogIP != nil {
+			syslogIP = *dhcp.SyslogIP
+		}
 	}
+
+	if ipxe := smee.IPXEConfigs; ipxe != nil {
+		if ipxe.TinkServerAddress != nil {
+			tinkServer = *ipxe.TinkServerAddress
+		}
+		if ipxe.HookURL != nil {
+			hookURL = *ipxe.HookURL
+		}
+		if ipxe.EnableTLS != nil {
+			enableTLS = *ipxe.EnableTLS
+		}
+		if len(ipxe.TrustedProxies) > 0 {
+			trustedProxies = ipxe.TrustedProxies
+		}
+	}
+
+	dataModel := []corev1.EnvVar{{Name: "DATA_MODEL_VERSION", Value: "kubernetes"}}
+	if file := smee.BackendConfigs.BackendFileMode; file != nil {
+		dataModel = []corev1.EnvVar{
+			{Name: "DATA_MODEL_VERSION", Value: "standalone"},
+			{Name: "BOOTS_STANDALONE_JSON", Value: file.FilePath},
+		}
+	}
+
+	return append(dataModel, []corev1.EnvVar{
+		{Name: "FACILITY_CODE", Value: "lab1"},
+		{Name: "PUBLIC_IP", Value: publicIP},
+		{Name: "PUBLIC_SYSLOG_FQDN", Value: syslogIP},
+		{Name: "MIRROR_BASE_URL", Value: hookURL},
+		{Name: "BOOTS_OSIE_PATH_OVERRIDE", Value: hookURL},
+		{Name: "TINKERBELL_GRPC_AUTHORITY", Value: tinkServer},
+		{Name: "TINKERBELL_TLS", Value: strconv.FormatBool(enableTLS)},
+		{Name: "TRUSTED_PROXIES", Value: strings.Join(trustedProxies, ",")},
+	}...)
 }
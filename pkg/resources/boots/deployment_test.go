@@ -0,0 +1,266 @@
+package boots
+
+import (
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/diff"
+	ptr "k8s.io/utils/pointer"
+)
+
+const testTinkWorkerImage = "quay.io/tinkerbell/tink-worker:v0.8.0"
+
+func TestArgs(t *testing.T) {
+	cfg := resources.Config{Namespace: "tinkerbell"}
+
+	tests := map[string]struct {
+		smee     *v1alpha2.Smee
+		cfg      resources.Config
+		expected []string
+	}{
+		"no smee spec": {
+			cfg: cfg,
+			expected: []string{
+				"--log-level=debug",
+				"--dhcp-addr=0.0.0.0:67",
+				"--syslog-addr=0.0.0.0:514",
+				"--ipxe-tftp-addr=0.0.0.0:69",
+				"--http-addr=0.0.0.0:80",
+				"--kube-namespace=tinkerbell",
+				"--ipxe-enable-http=true",
+				"--extra-kernel-args=tink_worker_image=" + testTinkWorkerImage,
+			},
+		},
+		"empty configs": {
+			smee: &v1alpha2.Smee{
+				DHCPConfigs:   &v1alpha2.DHCPConfigs{},
+				TFTPConfigs:   &v1alpha2.TFTPConfigs{},
+				SyslogConfigs: &v1alpha2.SyslogConfigs{},
+				IPXEConfigs:   &v1alpha2.IPXEConfigs{ExtraKernelArgs: ptr.String("")},
+			},
+			cfg: cfg,
+			expected: []string{
+				"--log-level=debug",
+				"--dhcp-addr=0.0.0.0:67",
+				"--syslog-addr=0.0.0.0:514",
+				"--ipxe-tftp-addr=0.0.0.0:69",
+				"--http-addr=0.0.0.0:80",
+				"--kube-namespace=tinkerbell",
+				"--ipxe-enable-http=true",
+				"--extra-kernel-args=tink_worker_image=" + testTinkWorkerImage,
+			},
+		},
+		"overrides": {
+			smee: &v1alpha2.Smee{
+				LogLevel: ptr.String("info"),
+				BackendConfigs: v1alpha2.BackendConfigs{
+					BackendKubeMode: &v1alpha2.BackendKubeMode{
+						KubeConfigFilePath: ptr.String("/kubeconfig"),
+						KubeAPIURL:         ptr.String("https://10.0.0.1:6443"),
+						KubeNamespace:      ptr.String("hardware"),
+					},
+				},
+				DHCPConfigs: &v1alpha2.DHCPConfigs{
+					IP:                    "192.168.1.10",
+					Port:                  1067,
+					TFTPAddress:           ptr.String("192.168.1.20"),
+					HTTPIPXEBinaryAddress: ptr.String("192.168.1.20:8080"),
+				},
+				TFTPConfigs:   &v1alpha2.TFTPConfigs{IP: "192.168.1.10", Port: 1069, TFTPTimeout: ptr.Int(10)},
+				SyslogConfigs: &v1alpha2.SyslogConfigs{Port: 1514},
+				IPXEConfigs: &v1alpha2.IPXEConfigs{
+					Port:             8081,
+					EnableHTTPBinary: ptr.Bool(false),
+					ExtraKernelArgs:  ptr.String("console=ttyS0"),
+				},
+			},
+			cfg: resources.Config{
+				Namespace:     "tinkerbell",
+				TinkServerTLS: &resources.TLS{CABundle: []byte("ca")},
+			},
+			expected: []string{
+				"--log-level=info",
+				"--dhcp-addr=192.168.1.10:1067",
+				"--syslog-addr=0.0.0.0:1514",
+				"--ipxe-tftp-addr=192.168.1.10:1069",
+				"--http-addr=0.0.0.0:8081",
+				"--kubeconfig=/kubeconfig",
+				"--kubernetes=https://10.0.0.1:6443",
+				"--kube-namespace=hardware",
+				"--ipxe-tftp-timeout=10s",
+				"--ipxe-enable-http=false",
+				"--extra-kernel-args=tink_worker_image=" + testTinkWorkerImage + " console=ttyS0 tinkerbell_tls_ca=Y2E=",
+				"--ipxe-remote-tftp-addr=192.168.1.20",
+				"--ipxe-remote-http-addr=192.168.1.20:8080",
+			},
+		},
+		"file backend": {
+			smee: &v1alpha2.Smee{
+				BackendConfigs: v1alpha2.BackendConfigs{
+					BackendFileMode: &v1alpha2.BackendFileMode{FilePath: "/hardware.yaml"},
+				},
+			},
+			cfg: cfg,
+			expected: []string{
+				"--log-level=debug",
+				"--dhcp-addr=0.0.0.0:67",
+				"--syslog-addr=0.0.0.0:514",
+				"--ipxe-tftp-addr=0.0.0.0:69",
+				"--http-addr=0.0.0.0:80",
+				"--ipxe-enable-http=true",
+				"--extra-kernel-args=tink_worker_image=" + testTinkWorkerImage,
+			},
+		},
+		"TLS without CA bundle": {
+			cfg: resources.Config{Namespace: "tinkerbell", TinkServerTLS: &resources.TLS{}},
+			expected: []string{
+				"--log-level=debug",
+				"--dhcp-addr=0.0.0.0:67",
+				"--syslog-addr=0.0.0.0:514",
+				"--ipxe-tftp-addr=0.0.0.0:69",
+				"--http-addr=0.0.0.0:80",
+				"--kube-namespace=tinkerbell",
+				"--ipxe-enable-http=true",
+				"--extra-kernel-args=tink_worker_image=" + testTinkWorkerImage,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := args(test.smee, test.cfg, testTinkWorkerImage)
+
+			if !apiequality.Semantic.DeepEqual(args, test.expected) {
+				t.Errorf("unexpected args:\n%s", diff.ObjectReflectDiff(test.expected, args))
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	cfg := resources.Config{
+		PublicIP:       "192.168.1.10",
+		TrustedProxies: []string{"10.244.0.0/24", "10.244.1.0/24"},
+	}
+
+	tests := map[string]struct {
+		smee     *v1alpha2.Smee
+		cfg      resources.Config
+		expected []corev1.EnvVar
+	}{
+		"no smee spec": {
+			cfg: cfg,
+			expected: []corev1.EnvVar{
+				{Name: "DATA_MODEL_VERSION", Value: "kubernetes"},
+				{Name: "FACILITY_CODE", Value: "lab1"},
+				{Name: "PUBLIC_IP", Value: "192.168.1.10"},
+				{Name: "PUBLIC_SYSLOG_FQDN", Value: "192.168.1.10"},
+				{Name: "MIRROR_BASE_URL", Value: "http://192.168.1.10:8080"},
+				{Name: "BOOTS_OSIE_PATH_OVERRIDE", Value: "http://192.168.1.10:8080"},
+				{Name: "TINKERBELL_GRPC_AUTHORITY", Value: "192.168.1.10:42113"},
+				{Name: "TINKERBELL_TLS", Value: "false"},
+				{Name: "TRUSTED_PROXIES", Value: "10.244.0.0/24,10.244.1.0/24"},
+			},
+		},
+		"public address being discovered": {
+			smee: &v1alpha2.Smee{DHCPConfigs: &v1alpha2.DHCPConfigs{}, IPXEConfigs: &v1alpha2.IPXEConfigs{}},
+			cfg:  resources.Config{TinkServerTLS: &resources.TLS{}},
+			expected: []corev1.EnvVar{
+				{Name: "DATA_MODEL_VERSION", Value: "kubernetes"},
+				{Name: "FACILITY_CODE", Value: "lab1"},
+				{Name: "PUBLIC_IP", Value: ""},
+				{Name: "PUBLIC_SYSLOG_FQDN", Value: ""},
+				{Name: "MIRROR_BASE_URL", Value: "http://:8080"},
+				{Name: "BOOTS_OSIE_PATH_OVERRIDE", Value: "http://:8080"},
+				{Name: "TINKERBELL_GRPC_AUTHORITY", Value: ":42113"},
+				{Name: "TINKERBELL_TLS", Value: "true"},
+				{Name: "TRUSTED_PROXIES", Value: ""},
+			},
+		},
+		"overrides": {
+			smee: &v1alpha2.Smee{
+				BackendConfigs: v1alpha2.BackendConfigs{
+					BackendFileMode: &v1alpha2.BackendFileMode{FilePath: "/hardware.yaml"},
+				},
+				DHCPConfigs: &v1alpha2.DHCPConfigs{
+					IPForPacket: ptr.String("192.168.1.20"),
+					SyslogIP:    ptr.String("192.168.1.30"),
+				},
+				IPXEConfigs: &v1alpha2.IPXEConfigs{
+					TinkServerAddress: ptr.String("tink.local:42114"),
+					HookURL:           ptr.String("http://artifacts.local/hook"),
+					EnableTLS:         ptr.Bool(false),
+					TrustedProxies:    []string{"10.0.0.0/8"},
+				},
+			},
+			cfg: resources.Config{
+				PublicIP:       "192.168.1.10",
+				TrustedProxies: []string{"10.244.0.0/24"},
+				TinkServerTLS:  &resources.TLS{},
+			},
+			expected: []corev1.EnvVar{
+				{Name: "DATA_MODEL_VERSION", Value: "standalone"},
+				{Name: "BOOTS_STANDALONE_JSON", Value: "/hardware.yaml"},
+				{Name: "FACILITY_CODE", Value: "lab1"},
+				{Name: "PUBLIC_IP", Value: "192.168.1.20"},
+				{Name: "PUBLIC_SYSLOG_FQDN", Value: "192.168.1.30"},
+				{Name: "MIRROR_BASE_URL", Value: "http://artifacts.local/hook"},
+				{Name: "BOOTS_OSIE_PATH_OVERRIDE", Value: "http://artifacts.local/hook"},
+				{Name: "TINKERBELL_GRPC_AUTHORITY", Value: "tink.local:42114"},
+				{Name: "TINKERBELL_TLS", Value: "false"},
+				{Name: "TRUSTED_PROXIES", Value: "10.0.0.0/8"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			env := env(test.smee, test.cfg, resources.TinkServerGRPCPort)
+
+			if !apiequality.Semantic.DeepEqual(env, test.expected) {
+				t.Errorf("unexpected env:\n%s", diff.ObjectReflectDiff(test.expected, env))
+			}
+		})
+	}
+}
+
+func TestListenPorts(t *testing.T) {
+	tests := map[string]struct {
+		smee     *v1alpha2.Smee
+		expected Ports
+	}{
+		"no smee spec": {
+			expected: Ports{DHCP: DefaultDHCPPort, HTTP: DefaultHTTPPort, Syslog: DefaultSyslogPort, TFTP: DefaultTFTPPort},
+		},
+		"empty configs": {
+			smee: &v1alpha2.Smee{
+				DHCPConfigs:   &v1alpha2.DHCPConfigs{},
+				TFTPConfigs:   &v1alpha2.TFTPConfigs{},
+				SyslogConfigs: &v1alpha2.SyslogConfigs{},
+				IPXEConfigs:   &v1alpha2.IPXEConfigs{},
+			},
+			expected: Ports{DHCP: DefaultDHCPPort, HTTP: DefaultHTTPPort, Syslog: DefaultSyslogPort, TFTP: DefaultTFTPPort},
+		},
+		"overrides": {
+			smee: &v1alpha2.Smee{
+				DHCPConfigs:   &v1alpha2.DHCPConfigs{Port: 1067},
+				TFTPConfigs:   &v1alpha2.TFTPConfigs{Port: 1069},
+				SyslogConfigs: &v1alpha2.SyslogConfigs{Port: 1514},
+				IPXEConfigs:   &v1alpha2.IPXEConfigs{Port: 8081},
+			},
+			expected: Ports{DHCP: 1067, HTTP: 8081, Syslog: 1514, TFTP: 1069},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if ports := ListenPorts(test.smee); ports != test.expected {
+				t.Errorf("expected the ports %+v, got %+v", test.expected, ports)
+			}
+		})
+	}
+}
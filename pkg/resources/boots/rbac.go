@@ -3,8 +3,10 @@ package boots
 import (
 	"context"
 
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
+
 	rbacv1 "k8s.io/api/rbac/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -14,10 +16,10 @@ const (
 	clusterRoleBinding = "boots-cluster-role-binding"
 )
 
-func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client) error {
+func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack) error {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: v1.ObjectMeta{
-			Name: clusterRole,
+			Name: util.ClusterScopedName(stack, clusterRole),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -33,21 +35,15 @@ func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client) err
 		},
 	}
 
-	if err := client.Create(ctx, clusterRole); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRole, stack, ComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRole)
 }
 
-func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: v1.ObjectMeta{
-			Name: clusterRoleBinding,
+			Name: util.ClusterScopedName(stack, clusterRoleBinding),
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -59,17 +55,11 @@ func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Clie
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
 			Kind:     "ClusterRole",
-			Name:     clusterRole,
+			Name:     util.ClusterScopedName(stack, clusterRole),
 		},
 	}
 
-	if err := client.Create(ctx, clusterRoleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRoleBinding, stack, ComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRoleBinding)
 }
@@ -2,18 +2,24 @@ package boots
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func CreateService(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateService(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
+	addrs := addresses(stack.Spec.Services.Smee)
+
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "boots",
+			Name:      ServiceName,
 			Namespace: ns,
 			Labels: map[string]string{
 				"app": "boots",
@@ -27,39 +33,37 @@ func CreateService(ctx context.Context, client ctrlruntimeclient.Client, ns stri
 			Ports: []corev1.ServicePort{
 				{
 					Name:       "boots-dhcp",
-					Port:       67,
-					TargetPort: intstr.FromInt(67),
+					Port:       int32(addrs.dhcp.port),
+					TargetPort: intstr.FromInt(addrs.dhcp.port),
 					Protocol:   corev1.ProtocolUDP,
 				},
 				{
 					Name:       "boots-http",
-					Port:       80,
-					TargetPort: intstr.FromInt(80),
+					Port:       int32(addrs.http.port),
+					TargetPort: intstr.FromInt(addrs.http.port),
 					Protocol:   corev1.ProtocolTCP,
 				},
 				{
 					Name:       "boots-syslog",
-					Port:       514,
-					TargetPort: intstr.FromInt(514),
+					Port:       int32(addrs.syslog.port),
+					TargetPort: intstr.FromInt(addrs.syslog.port),
 					Protocol:   corev1.ProtocolUDP,
 				},
 				{
 					Name:       "boots-tftp",
-					Port:       69,
-					TargetPort: intstr.FromInt(69),
+					Port:       int32(addrs.tftp.port),
+					TargetPort: intstr.FromInt(addrs.tftp.port),
 					Protocol:   corev1.ProtocolUDP,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, service); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(service, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, service, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, service)
 }
@@ -0,0 +1,48 @@
+package resources
+
+import (
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/release"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Config contains the values required to render the stack resources which are not part of the Stack spec, such as
+// operator flags or values discovered from the cluster.
+type Config struct {
+	// Namespace is the namespace the stack resources are deployed in.
+	Namespace string
+	// Release contains the component images of the Stack version.
+	Release release.Release
+	// Registry replaces the registry of all the stack images if set.
+	Registry string
+	// ImagePullSecrets are attached to every stack service account and pod.
+	ImagePullSecrets []corev1.LocalObjectReference
+	// ClusterDNS is the IP address of the cluster DNS resolver used by the nginx proxy.
+	ClusterDNS string
+	// ClusterDomain is the DNS domain of the cluster, e.g. cluster.local.
+	ClusterDomain string
+	// PublicIP is the address the provisioned machines use to reach the stack services. It is empty while the address
+	// is being discovered.
+	PublicIP string
+	// TrustedProxies are the pod CIDRs of the cluster nodes. The services trust the X-Forwarded-For header of requests
+	// coming from them, unless the Stack overrides the trusted proxies.
+	TrustedProxies []string
+	// TinkServerTLS contains the tink-server serving certificate, it is nil if TLS is disabled.
+	TinkServerTLS *TLS
+	// HookDownloaderImage is the operator image the Hook artifacts are downloaded with.
+	HookDownloaderImage string
+	// HookArchives are the Hook archives the nginx pods download themselves. They are only set for the EmptyDir
+	// storage, the other storages are published by the Hook reconciler.
+	HookArchives []hook.Archive
+}
+
+// TLS describes a serving certificate the operator mounts into a stack service.
+type TLS struct {
+	// SecretName is the name of the kubernetes.io/tls secret containing the serving certificate.
+	SecretName string
+	// CABundle is the PEM encoded CA bundle clients use to verify the serving certificate.
+	CABundle []byte
+	// Checksum changes whenever the serving certificate changes, so that the service is restarted with it.
+	Checksum string
+}
@@ -0,0 +1,45 @@
+package resources
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultResources returns the compute resources of a stack component container whose spec doesn't set any.
+func DefaultResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+		},
+	}
+}
+
+// DefaultHookDownloadResources returns the compute resources of the Hook download containers whose spec doesn't set
+// any. The downloader buffers the archives while it verifies them, so it gets more memory than the other components.
+func DefaultHookDownloadResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+		},
+	}
+}
+
+// ContainerResources returns the given compute resources of a stack component container, falling back to the default
+// resources if they are not set.
+func ContainerResources(resources *corev1.ResourceRequirements) corev1.ResourceRequirements {
+	if resources == nil {
+		return DefaultResources()
+	}
+
+	return *resources.DeepCopy()
+}
@@ -0,0 +1,61 @@
+package resources
+
+import (
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ptr "k8s.io/utils/pointer"
+)
+
+// ApplyComponentDeployment customizes the deployment of a stack component with the deployment settings of its spec.
+// The extra labels and annotations don't override the ones the builder sets, so the pod selector keeps matching.
+func ApplyComponentDeployment(deployment *appsv1.Deployment, settings v1alpha2.ComponentDeployment) {
+	settings = *settings.DeepCopy()
+
+	if settings.Replicas != nil {
+		deployment.Spec.Replicas = ptr.Int32(*settings.Replicas)
+	}
+
+	applyPodSettings(&deployment.Spec.Template.Spec, settings, &deployment.ObjectMeta, &deployment.Spec.Template.ObjectMeta)
+}
+
+// ApplyComponentJob customizes the Job of a stack component with the deployment settings of its spec. The replicas
+// don't apply to a Job, a node selector the builder sets is kept.
+func ApplyComponentJob(job *batchv1.Job, settings v1alpha2.ComponentDeployment) {
+	settings = *settings.DeepCopy()
+
+	settings.NodeSelector = addMissing(job.Spec.Template.Spec.NodeSelector, settings.NodeSelector)
+	applyPodSettings(&job.Spec.Template.Spec, settings, &job.ObjectMeta, &job.Spec.Template.ObjectMeta)
+}
+
+func applyPodSettings(podSpec *corev1.PodSpec, settings v1alpha2.ComponentDeployment, metas ...*metav1.ObjectMeta) {
+	podSpec.NodeSelector = settings.NodeSelector
+	podSpec.Tolerations = settings.Tolerations
+	podSpec.Affinity = settings.Affinity
+	podSpec.TopologySpreadConstraints = settings.TopologySpreadConstraints
+	podSpec.PriorityClassName = settings.PriorityClassName
+
+	for _, meta := range metas {
+		meta.Labels = addMissing(meta.Labels, settings.Labels)
+		meta.Annotations = addMissing(meta.Annotations, settings.Annotations)
+	}
+}
+
+// addMissing adds the entries of extra to the given map whose keys are not set yet.
+func addMissing(m, extra map[string]string) map[string]string {
+	for k, v := range extra {
+		if _, ok := m[k]; ok {
+			continue
+		}
+
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[k] = v
+	}
+
+	return m
+}
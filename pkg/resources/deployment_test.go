@@ -0,0 +1,159 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+	ptr "k8s.io/utils/pointer"
+)
+
+func testDeployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "hegel",
+			Labels: map[string]string{"app": "hegel"},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Int32(1),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"app": "hegel", "stack": "tinkerbell"},
+				},
+			},
+		},
+	}
+}
+
+func TestApplyComponentDeployment(t *testing.T) {
+	tolerations := []corev1.Toleration{{Key: "tinkerbell.org/provisioning", Operator: corev1.TolerationOpExists}}
+	affinity := &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: corev1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "hegel"}},
+					TopologyKey:   corev1.LabelHostname,
+				},
+			}},
+		},
+	}
+	topologySpreadConstraints := []corev1.TopologySpreadConstraint{{
+		MaxSkew:           1,
+		TopologyKey:       corev1.LabelTopologyZone,
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+	}}
+
+	tests := map[string]struct {
+		settings v1alpha2.ComponentDeployment
+		expected func(*appsv1.Deployment)
+	}{
+		"empty settings": {
+			expected: func(*appsv1.Deployment) {},
+		},
+		"overrides": {
+			settings: v1alpha2.ComponentDeployment{
+				Replicas:                  ptr.Int32(3),
+				NodeSelector:              map[string]string{"kubernetes.io/os": "linux"},
+				Tolerations:               tolerations,
+				Affinity:                  affinity,
+				TopologySpreadConstraints: topologySpreadConstraints,
+				PriorityClassName:         "system-cluster-critical",
+			},
+			expected: func(d *appsv1.Deployment) {
+				d.Spec.Replicas = ptr.Int32(3)
+				d.Spec.Template.Spec.NodeSelector = map[string]string{"kubernetes.io/os": "linux"}
+				d.Spec.Template.Spec.Tolerations = tolerations
+				d.Spec.Template.Spec.Affinity = affinity
+				d.Spec.Template.Spec.TopologySpreadConstraints = topologySpreadConstraints
+				d.Spec.Template.Spec.PriorityClassName = "system-cluster-critical"
+			},
+		},
+		"extra labels and annotations": {
+			settings: v1alpha2.ComponentDeployment{
+				Labels:      map[string]string{"team": "metal"},
+				Annotations: map[string]string{"prometheus.io/scrape": "true"},
+			},
+			expected: func(d *appsv1.Deployment) {
+				d.Labels["team"] = "metal"
+				d.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+				d.Spec.Template.Labels["team"] = "metal"
+				d.Spec.Template.Annotations = map[string]string{"prometheus.io/scrape": "true"}
+			},
+		},
+		"labels of the builder are kept": {
+			settings: v1alpha2.ComponentDeployment{
+				Labels: map[string]string{"app": "metadata", "stack": "other"},
+			},
+			expected: func(d *appsv1.Deployment) {
+				d.Labels["stack"] = "other"
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			deployment := testDeployment()
+			settings := *test.settings.DeepCopy()
+			ApplyComponentDeployment(deployment, settings)
+
+			expected := testDeployment()
+			test.expected(expected)
+
+			if !apiequality.Semantic.DeepEqual(deployment, expected) {
+				t.Errorf("unexpected deployment:\n%s", diff.ObjectReflectDiff(expected, deployment))
+			}
+
+			// The settings of the stack are not shared with the deployment.
+			if !apiequality.Semantic.DeepEqual(settings, test.settings) {
+				t.Errorf("expected the settings to be unchanged:\n%s", diff.ObjectReflectDiff(test.settings, settings))
+			}
+		})
+	}
+}
+
+func TestApplyComponentJob(t *testing.T) {
+	tests := map[string]struct {
+		nodeSelector map[string]string
+		settings     v1alpha2.ComponentDeployment
+		expected     map[string]string
+	}{
+		"no node selectors": {},
+		"node selector of the settings": {
+			settings: v1alpha2.ComponentDeployment{NodeSelector: map[string]string{"kubernetes.io/os": "linux"}},
+			expected: map[string]string{"kubernetes.io/os": "linux"},
+		},
+		"node selector of the builder is kept": {
+			nodeSelector: map[string]string{corev1.LabelHostname: "node-1"},
+			settings: v1alpha2.ComponentDeployment{
+				NodeSelector: map[string]string{corev1.LabelHostname: "node-2", "kubernetes.io/os": "linux"},
+			},
+			expected: map[string]string{corev1.LabelHostname: "node-1", "kubernetes.io/os": "linux"},
+		},
+		"replicas don't apply": {
+			settings: v1alpha2.ComponentDeployment{Replicas: ptr.Int32(3)},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			job := &batchv1.Job{}
+			job.Spec.Template.Spec.NodeSelector = test.nodeSelector
+			ApplyComponentJob(job, test.settings)
+
+			if !apiequality.Semantic.DeepEqual(job.Spec.Template.Spec.NodeSelector, test.expected) {
+				t.Errorf("expected the node selector %v, got %v", test.expected, job.Spec.Template.Spec.NodeSelector)
+			}
+
+			if job.Spec.Parallelism != nil || job.Spec.Completions != nil {
+				t.Errorf("expected the replicas not to be applied, got parallelism %v and completions %v", job.Spec.Parallelism, job.Spec.Completions)
+			}
+		})
+	}
+}
@@ -2,21 +2,49 @@ package hegel
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ptr "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ComponentName is the name of the hegel component in the Stack.
+	ComponentName = "hegel"
+	// DeploymentName is the name of the hegel deployment.
+	DeploymentName = "hegel"
+	// ServiceName is the name of the hegel service.
+	ServiceName = "hegel"
 )
 
-func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	var image v1alpha2.Image
+	var settings v1alpha2.ComponentDeployment
+	trustedProxies := strings.Join(cfg.TrustedProxies, ",")
+	port := resources.HegelPort(stack)
+
+	if hegel := stack.Spec.Services.Hegel; hegel != nil {
+		image = hegel.Image
+		settings = hegel.ComponentDeployment
+		if len(hegel.TrustedProxies) > 0 {
+			trustedProxies = strings.Join(hegel.TrustedProxies, ",")
+		}
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "hegel",
-			Namespace: ns,
+			Name:      DeploymentName,
+			Namespace: cfg.Namespace,
 			Labels: map[string]string{
 				"app": "hegel",
 			},
@@ -40,47 +68,37 @@ func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns s
 					Containers: []corev1.Container{
 						{
 							Name:            "hegel",
-							Image:           "quay.io/tinkerbell/hegel:v0.8.0",
+							Image:           util.Image(image, cfg.Release.Hegel.Repository, cfg.Release.Hegel.Tag, cfg.Registry),
 							ImagePullPolicy: corev1.PullIfNotPresent,
-							Args:            []string{"--data-model", "kubernetes", "--kube-namespace", ns, "--http-port", "50061"},
+							Args:            []string{"--data-model", "kubernetes", "--kube-namespace", cfg.Namespace, "--http-port", strconv.Itoa(port)},
 							Env: []corev1.EnvVar{
 								{
-									Name: "HEGEL_TRUSTED_PROXIES",
-									// TODO: pass the TRUSTED_PROXIES as a command line
-									Value: "10.244.0.0/24,10.244.1.0/24,10.244.2.0/24",
-								},
-							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
+									Name:  "HEGEL_TRUSTED_PROXIES",
+									Value: trustedProxies,
 								},
 							},
+							Resources: resources.ContainerResources(settings.Resources),
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: int32(50061),
+									ContainerPort: int32(port),
 									Name:          "hegel-http",
 								},
 							},
 						},
 					},
 					ServiceAccountName: serviceAccountName,
+					ImagePullSecrets:   cfg.ImagePullSecrets,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	resources.ApplyComponentDeployment(deployment, settings)
+	util.SetStackLabels(deployment, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, deployment)
 }
@@ -2,11 +2,15 @@ package hegel
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	rbacv1 "k8s.io/api/rbac/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -14,7 +18,7 @@ const (
 	roleBinding = "hegel-role-binding"
 )
 
-func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	role := &rbacv1.Role{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      role,
@@ -34,18 +38,16 @@ func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string)
 		},
 	}
 
-	if err := client.Create(ctx, role); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(role, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, role, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, role)
 }
 
-func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	roleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      roleBinding,
@@ -65,13 +67,11 @@ func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns
 		},
 	}
 
-	if err := client.Create(ctx, roleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(roleBinding, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, roleBinding, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, roleBinding)
 }
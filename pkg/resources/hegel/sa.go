@@ -2,32 +2,36 @@ package hegel
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
 	serviceAccountName = "hegel"
 )
 
-func CreateServiceAccount(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateServiceAccount(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
 	sa := &corev1.ServiceAccount{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      serviceAccountName,
-			Namespace: ns,
+			Namespace: cfg.Namespace,
 		},
+		ImagePullSecrets: cfg.ImagePullSecrets,
 	}
 
-	if err := client.Create(ctx, sa); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(sa, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, sa, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, sa)
 }
@@ -2,18 +2,23 @@ package hegel
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func CreateService(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateService(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "hegel",
+			Name:      ServiceName,
 			Namespace: ns,
 			Labels: map[string]string{
 				"app": "hegel",
@@ -26,21 +31,19 @@ func CreateService(ctx context.Context, client ctrlruntimeclient.Client, ns stri
 			},
 			Ports: []corev1.ServicePort{
 				{
-					Port:       50061,
-					TargetPort: intstr.IntOrString{IntVal: 50061},
+					Port:       int32(resources.HegelPort(stack)),
+					TargetPort: intstr.FromInt(resources.HegelPort(stack)),
 					Protocol:   corev1.ProtocolTCP,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, service); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(service, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, service, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, service)
 }
@@ -0,0 +1,39 @@
+package resources
+
+import (
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+)
+
+const (
+	// TinkServerGRPCPort is the default port tink-server serves its gRPC API on.
+	TinkServerGRPCPort = 42113
+	// HegelHTTPPort is the default port hegel serves the instance metadata on.
+	HegelHTTPPort = 50061
+	// HookHTTPPort is the port the nginx proxy serves the Hook artifacts on.
+	HookHTTPPort = 8080
+)
+
+// ServiceHost returns the fully qualified DNS name of the given Service of the stack.
+func ServiceHost(name string, cfg Config) string {
+	return fmt.Sprintf("%s.%s.svc.%s", name, cfg.Namespace, cfg.ClusterDomain)
+}
+
+// TinkServerPort returns the port tink-server of the given stack serves its gRPC API on.
+func TinkServerPort(stack *v1alpha2.Stack) int {
+	if tinkServer := stack.Spec.Services.TinkServer; tinkServer != nil && tinkServer.Port != 0 {
+		return tinkServer.Port
+	}
+
+	return TinkServerGRPCPort
+}
+
+// HegelPort returns the port hegel of the given stack serves the instance metadata on.
+func HegelPort(stack *v1alpha2.Stack) int {
+	if hegel := stack.Spec.Services.Hegel; hegel != nil && hegel.Port != 0 {
+		return hegel.Port
+	}
+
+	return HegelHTTPPort
+}
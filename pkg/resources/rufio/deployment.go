@@ -2,22 +2,41 @@ package rufio
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ptr "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ComponentName is the name of the rufio component in the Stack.
+	ComponentName = "rufio"
+	// DeploymentName is the name of the rufio deployment.
+	DeploymentName = "rufio"
 )
 
-func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	var image v1alpha2.Image
+	var settings v1alpha2.ComponentDeployment
+	if rufio := stack.Spec.Services.Rufio; rufio != nil {
+		image = rufio.Image
+		settings = rufio.ComponentDeployment
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rufio",
-			Namespace: ns,
+			Name:      DeploymentName,
+			Namespace: cfg.Namespace,
 			Labels: map[string]string{
 				"app":           "rufio",
 				"control-plane": "controller-manager",
@@ -51,16 +70,15 @@ func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns s
 						{
 							Name:    "manager",
 							Command: []string{"/manager"},
-							Image:   "quay.io/tinkerbell/rufio:v0.1.0",
+							Image:   util.Image(image, cfg.Release.Rufio.Repository, cfg.Release.Rufio.Tag, cfg.Registry),
 							SecurityContext: &corev1.SecurityContext{
 								AllowPrivilegeEscalation: ptr.Bool(false),
 							},
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Env: []corev1.EnvVar{
 								{
-									Name: "HEGEL_TRUSTED_PROXIES",
-									// TODO: pass the TRUSTED_PROXIES as a command line
-									Value: "10.244.0.0/24,10.244.1.0/24,10.244.2.0/24",
+									Name:  "HEGEL_TRUSTED_PROXIES",
+									Value: strings.Join(cfg.TrustedProxies, ","),
 								},
 							},
 							LivenessProbe: &corev1.Probe{
@@ -83,16 +101,7 @@ func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns s
 								InitialDelaySeconds: 5,
 								PeriodSeconds:       10,
 							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
+							Resources: resources.ContainerResources(settings.Resources),
 							Ports: []corev1.ContainerPort{
 								{
 									ContainerPort: int32(50061),
@@ -102,19 +111,19 @@ func CreateDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns s
 						},
 					},
 					ServiceAccountName:            serviceAccountName,
+					ImagePullSecrets:              cfg.ImagePullSecrets,
 					TerminationGracePeriodSeconds: ptr.Int64(10),
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	resources.ApplyComponentDeployment(deployment, settings)
+	util.SetStackLabels(deployment, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, deployment)
 }
@@ -2,11 +2,15 @@ package rufio
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	rbacv1 "k8s.io/api/rbac/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -17,10 +21,10 @@ const (
 	roleBinding = "rufio-leader-election-role-binding"
 )
 
-func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client) error {
+func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack) error {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: v1.ObjectMeta{
-			Name: clusterRole,
+			Name: util.ClusterScopedName(stack, clusterRole),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -71,21 +75,15 @@ func CreateClusterRole(ctx context.Context, client ctrlruntimeclient.Client) err
 		},
 	}
 
-	if err := client.Create(ctx, clusterRole); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRole, stack, ComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRole)
 }
 
-func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: v1.ObjectMeta{
-			Name: clusterRoleBinding,
+			Name: util.ClusterScopedName(stack, clusterRoleBinding),
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -97,22 +95,16 @@ func CreateClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Clie
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
 			Kind:     "ClusterRole",
-			Name:     clusterRole,
+			Name:     util.ClusterScopedName(stack, clusterRole),
 		},
 	}
 
-	if err := client.Create(ctx, clusterRoleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRoleBinding, stack, ComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRoleBinding)
 }
 
-func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	role := &rbacv1.Role{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      role,
@@ -137,18 +129,16 @@ func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string)
 		},
 	}
 
-	if err := client.Create(ctx, role); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(role, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, role, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, role)
 }
 
-func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	roleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      roleBinding,
@@ -168,13 +158,11 @@ func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns
 		},
 	}
 
-	if err := client.Create(ctx, roleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(roleBinding, stack, ComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, roleBinding, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, roleBinding)
 }
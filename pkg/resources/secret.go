@@ -0,0 +1,42 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// ImagePullSecretName is the name of the secret created from the operator --docker-pull-config-json-file flag.
+	ImagePullSecretName = "tinkerbell-image-pull-secret"
+
+	stackComponentName = "stack"
+)
+
+func CreateImagePullSecret(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, dockerConfigJSON []byte, ns string) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ImagePullSecretName,
+			Namespace: ns,
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfigJSON,
+		},
+	}
+
+	util.SetStackLabels(secret, stack, stackComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, secret, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, secret)
+}
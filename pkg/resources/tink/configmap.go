@@ -6,51 +6,93 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+	"github.com/tinkerbell/operator/pkg/resources/hegel"
+	"github.com/tinkerbell/operator/pkg/util"
+
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
-func CreateNginxConfigMap(ctx context.Context, client ctrlruntimeclient.Client, clusterDNS, ns string) error {
-	tmpl, err := template.New("nginx-conf").Parse(nginxConfigData)
+func CreateNginxConfigMap(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	nginxConfig, err := renderNginxConfig(stack, cfg)
 	if err != nil {
-		return fmt.Errorf("failed to parse nginx-conf template: %w", err)
-	}
-
-	data := struct {
-		ClusterDNS string
-	}{
-		ClusterDNS: clusterDNS,
-	}
-
-	var buf strings.Builder
-	if err = tmpl.Execute(&buf, data); err != nil {
-		return fmt.Errorf("failed to execute nginx-conf template: %w", err)
+		return err
 	}
 
 	nginxConf := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "nginx-conf",
-			Namespace: ns,
+			Namespace: cfg.Namespace,
 		},
 		Data: map[string]string{
-			"nginx.conf": buf.String(),
+			"nginx.conf": nginxConfig,
 		},
 	}
 
-	if err := client.Create(ctx, nginxConf); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(nginxConf, stack, NginxComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, nginxConf, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, nginxConf)
+}
+
+// nginxUpstream is a stack service the nginx proxy forwards to.
+type nginxUpstream struct {
+	Host string
+	Port int
+}
+
+// smeeUpstream contains the smee service and the ports the nginx proxy forwards to it.
+type smeeUpstream struct {
+	Host  string
+	Ports boots.Ports
+}
+
+// renderNginxConfig renders the nginx config of the stack. The nginx deployment renders it as well to stamp its
+// checksum on the pod template.
+func renderNginxConfig(stack *v1alpha2.Stack, cfg resources.Config) (string, error) {
+	tmpl, err := template.New("nginx-conf").Parse(nginxConfigData)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse nginx-conf template: %w", err)
+	}
+
+	data := struct {
+		ClusterDNS    string
+		Smee          *smeeUpstream
+		Hegel         *nginxUpstream
+		TinkServer    nginxUpstream
+		TinkServerTLS bool
+		HookPort      int
+	}{
+		ClusterDNS:    cfg.ClusterDNS,
+		TinkServer:    nginxUpstream{Host: resources.ServiceHost(TinkServerServiceName, cfg), Port: resources.TinkServerPort(stack)},
+		TinkServerTLS: cfg.TinkServerTLS != nil,
+		HookPort:      resources.HookHTTPPort,
+	}
+
+	if smee := stack.Spec.Services.Smee; smee != nil {
+		data.Smee = &smeeUpstream{Host: resources.ServiceHost(boots.ServiceName, cfg), Ports: boots.ListenPorts(smee)}
+	}
+
+	if stack.Spec.Services.Hegel != nil {
+		data.Hegel = &nginxUpstream{Host: resources.ServiceHost(hegel.ServiceName, cfg), Port: resources.HegelPort(stack)}
+	}
+
+	var buf strings.Builder
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute nginx-conf template: %w", err)
+	}
+
+	return buf.String(), nil
 }
 
-// TODO: parse nginx configs from args/operator configs
 var nginxConfigData = `
 worker_processes 1;
 events {
@@ -59,44 +101,50 @@ events {
 user root;
 
 http {
+{{- with .Smee }}
   server {
-    listen 80;
+    listen {{ .Ports.HTTP }};
     location / {
       proxy_set_header X-Real-IP $remote_addr;
       proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-      resolver {{ .ClusterDNS }};
-      set $boots_dns boots.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
+      resolver {{ $.ClusterDNS }};
+      set $boots_dns {{ .Host }}; # needed in Kubernetes for dynamic DNS resolution
 
-      proxy_pass http://$boots_dns;
+      proxy_pass http://$boots_dns:{{ .Ports.HTTP }};
     }
   }
+{{- end }}
+{{- with .Hegel }}
 
   server {
-    listen 50061;
+    listen {{ .Port }};
     location / {
       proxy_set_header X-Real-IP $remote_addr;
       proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
-      resolver {{ .ClusterDNS }};
-      set $hegel_dns hegel.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
+      resolver {{ $.ClusterDNS }};
+      set $hegel_dns {{ .Host }}; # needed in Kubernetes for dynamic DNS resolution
 
-      proxy_pass http://$hegel_dns:50061;
+      proxy_pass http://$hegel_dns:{{ .Port }};
     }
   }
+{{- end }}
+{{- if not .TinkServerTLS }}
 
   server {
-    listen 42113 http2;
+    listen {{ .TinkServer.Port }} http2;
     location / {
       proxy_set_header X-Real-IP $remote_addr;
       proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
       resolver {{ .ClusterDNS }};
-      set $tink_dns tink-server.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
+      set $tink_dns {{ .TinkServer.Host }}; # needed in Kubernetes for dynamic DNS resolution
 
-      grpc_pass grpc://$tink_dns:42113;
+      grpc_pass grpc://$tink_dns:{{ .TinkServer.Port }};
     }
   }
+{{- end }}
 
    server {
-    listen 8080;
+    listen {{ .HookPort }};
     location / {
       root /usr/share/nginx/html;
     }
@@ -105,31 +153,44 @@ http {
 
 stream {
   log_format logger-json escape=json '{"source": "nginx", "time": $msec, "address": "$remote_addr", "status": $status, "upstream_addr": "$upstream_addr"}';
+{{- if .TinkServerTLS }}
+
+  # tink-server terminates TLS itself, so the connections are passed through.
+  server {
+      listen {{ .TinkServer.Port }};
+      resolver {{ .ClusterDNS }};
+      set $tink_dns {{ .TinkServer.Host }}; # needed in Kubernetes for dynamic DNS resolution
+      proxy_pass $tink_dns:{{ .TinkServer.Port }};
+      access_log /dev/stdout logger-json;
+  }
+{{- end }}
+{{- with .Smee }}
 
   server {
-      listen 67 udp;
-      resolver {{ .ClusterDNS }}; # needed in Kubernetes for dynamic DNS resolution
-      set $boots_dns boots.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
-      proxy_pass $boots_dns:67;
+      listen {{ .Ports.DHCP }} udp;
+      resolver {{ $.ClusterDNS }}; # needed in Kubernetes for dynamic DNS resolution
+      set $boots_dns {{ .Host }}; # needed in Kubernetes for dynamic DNS resolution
+      proxy_pass $boots_dns:{{ .Ports.DHCP }};
       proxy_bind $remote_addr:$remote_port transparent;
       proxy_responses 0;
       access_log /dev/stdout logger-json;
   }
   server {
-      listen 69 udp;
-      resolver {{ .ClusterDNS }};
-      set $boots_dns boots.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
-      proxy_pass $boots_dns:69;
+      listen {{ .Ports.TFTP }} udp;
+      resolver {{ $.ClusterDNS }};
+      set $boots_dns {{ .Host }}; # needed in Kubernetes for dynamic DNS resolution
+      proxy_pass $boots_dns:{{ .Ports.TFTP }};
       proxy_timeout 1s;
       access_log /dev/stdout logger-json;
   }
   server {
-      listen 514 udp;
-      resolver {{ .ClusterDNS }};
-      set $boots_dns boots.tinkerbell.svc.cluster.local; # needed in Kubernetes for dynamic DNS resolution
-      proxy_pass $boots_dns:514;
+      listen {{ .Ports.Syslog }} udp;
+      resolver {{ $.ClusterDNS }};
+      set $boots_dns {{ .Host }}; # needed in Kubernetes for dynamic DNS resolution
+      proxy_pass $boots_dns:{{ .Ports.Syslog }};
       proxy_bind $remote_addr:$remote_port transparent;
       proxy_responses 0;
       access_log /dev/stdout logger-json;
   }
+{{- end }}
 }`
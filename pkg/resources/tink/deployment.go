@@ -2,23 +2,58 @@ package tink
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ptr "k8s.io/utils/pointer"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// TinkControllerComponentName is the name of the tink controller component in the Stack.
+	TinkControllerComponentName = "tink-controller"
+	// TinkServerComponentName is the name of the tink server component in the Stack.
+	TinkServerComponentName = "tink-server"
+	// NginxComponentName is the name of the nginx component in the Stack.
+	NginxComponentName = "nginx"
+
+	// TinkControllerDeploymentName is the name of the tink controller deployment.
+	TinkControllerDeploymentName = "tink-controller"
+	// TinkServerDeploymentName is the name of the tink server deployment.
+	TinkServerDeploymentName = "tink-server"
+	// NginxDeploymentName is the name of the nginx deployment proxying to the tinkerbell services.
+	NginxDeploymentName = "nginx-server"
+
+	// TinkServerServiceName is the name of the tink server service.
+	TinkServerServiceName = "tink-server"
+
+	defaultNginxImageRepository = "nginx"
+	defaultNginxImageTag        = "1.23.1"
+
+	// nginxConfigPath is the main config file of the nginx image, it is replaced by the rendered config of the stack.
+	nginxConfigPath = "/etc/nginx/nginx.conf"
 )
 
-var hostPathType = corev1.HostPathDirectoryOrCreate
+func CreateTinkControllerDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	// tink-controller is deployed with the defaults if it is not set.
+	tinkController := stack.Spec.Services.TinkController
+	if tinkController == nil {
+		tinkController = &v1alpha2.TinkController{}
+	}
 
-func CreateTinkControllerDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "tink-controller",
-			Namespace: ns,
+			Name:      TinkControllerDeploymentName,
+			Namespace: cfg.Namespace,
 			Labels: map[string]string{
 				"app": "tink-controller",
 			},
@@ -40,42 +75,39 @@ func CreateTinkControllerDeployment(ctx context.Context, client ctrlruntimeclien
 					Containers: []corev1.Container{
 						{
 							Name:            "tink-controller",
-							Image:           "quay.io/tinkerbell/tink-controller:v0.8.0",
+							Image:           util.Image(tinkController.Image, cfg.Release.TinkController.Repository, cfg.Release.TinkController.Tag, cfg.Registry),
 							ImagePullPolicy: corev1.PullIfNotPresent,
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
+							Resources:       resources.ContainerResources(tinkController.Resources),
 						},
 					},
 					ServiceAccountName: tinkControllerServiceAccountName,
+					ImagePullSecrets:   cfg.ImagePullSecrets,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	resources.ApplyComponentDeployment(deployment, tinkController.ComponentDeployment)
+	util.SetStackLabels(deployment, stack, TinkControllerComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, deployment)
 }
 
-func CreateTinkServerDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateTinkServerDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	// tink-server is deployed with the defaults if it is not set.
+	tinkServer := stack.Spec.Services.TinkServer
+	if tinkServer == nil {
+		tinkServer = &v1alpha2.TinkServer{}
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "tink-server",
-			Namespace: ns,
+			Name:      TinkServerDeploymentName,
+			Namespace: cfg.Namespace,
 			Labels: map[string]string{
 				"app": "tink-server",
 			},
@@ -99,58 +131,120 @@ func CreateTinkServerDeployment(ctx context.Context, client ctrlruntimeclient.Cl
 					Containers: []corev1.Container{
 						{
 							Name:  "server",
-							Image: "quay.io/tinkerbell/tink:v0.8.0",
-							Args:  []string{"--backend", "kubernetes"},
+							Image: util.Image(tinkServer.Image, cfg.Release.TinkServer.Repository, cfg.Release.TinkServer.Tag, cfg.Registry),
+							Args:  []string{"--backend", "kubernetes", "--grpc-authority", fmt.Sprintf(":%d", resources.TinkServerPort(stack))},
 							Env: []corev1.EnvVar{
 								{
 									Name:  "TINKERBELL_TLS",
-									Value: "false",
+									Value: strconv.FormatBool(cfg.TinkServerTLS != nil),
 								},
 							},
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Ports: []corev1.ContainerPort{
 								{
-									ContainerPort: int32(42113),
+									ContainerPort: int32(resources.TinkServerPort(stack)),
 									Name:          "tink-grpc",
 								},
 							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
+							Resources: resources.ContainerResources(tinkServer.Resources),
 						},
 					},
 					ServiceAccountName: tinkServerServiceAccountName,
+					ImagePullSecrets:   cfg.ImagePullSecrets,
 				},
 			},
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	if tls := cfg.TinkServerTLS; tls != nil {
+		mountTinkServerTLS(&deployment.Spec.Template, tls)
+	}
 
-		return err
+	resources.ApplyComponentDeployment(deployment, tinkServer.ComponentDeployment)
+	util.SetStackLabels(deployment, stack, TinkServerComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, deployment)
 }
 
-func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+// mountTinkServerTLS mounts the serving certificate into the tink-server pod the way tink-server expects it in its certs
+// directory. The certificate checksum restarts tink-server whenever the certificate is renewed.
+func mountTinkServerTLS(template *corev1.PodTemplateSpec, tls *resources.TLS) {
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations["checksum/tls"] = tls.Checksum
+
+	container := &template.Spec.Containers[0]
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "TINKERBELL_CERTS_DIR",
+		Value: tinkServerCertsDir,
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      "tls",
+		MountPath: tinkServerCertsDir,
+		ReadOnly:  true,
+	})
+
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: "tls",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: tls.SecretName,
+				Items: []corev1.KeyToPath{
+					{
+						Key:  corev1.TLSCertKey,
+						Path: "bundle.pem",
+					},
+					{
+						Key:  corev1.TLSPrivateKeyKey,
+						Path: "server-key.pem",
+					},
+				},
+			},
+		},
+	})
+}
+
+func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	nginxConfig, err := renderNginxConfig(stack, cfg)
+	if err != nil {
+		return err
+	}
+
+	storage := HookStorage(stack)
+
+	var settings v1alpha2.ComponentDeployment
+	if stack.Spec.Nginx != nil {
+		settings = stack.Spec.Nginx.ComponentDeployment
+	}
+
+	// Every pod downloads the artifacts into its own emptyDir volume, the other storages are published by a Job.
+	var initContainers []corev1.Container
+	var hookVolumes []corev1.Volume
+	if storage.Type == v1alpha2.HookStorageTypeEmptyDir {
+		initContainers, hookVolumes, err = hookDownloadContainers(stack, cfg, cfg.HookDownloaderImage, cfg.HookArchives)
+		if err != nil {
+			return err
+		}
+	}
+
+	// A volume which can only be attached to a single node can't be shared by the old and the new pod of a rollout.
+	strategy := appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}
+	if storage.Type == v1alpha2.HookStorageTypePersistentVolumeClaim && HookStorageNodeLocal(storage) {
+		strategy = appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "nginx-server",
-			Namespace: ns,
+			Name:      NginxDeploymentName,
+			Namespace: cfg.Namespace,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: ptr.Int32(1),
+			Strategy: strategy,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": "nginx-server",
@@ -159,8 +253,7 @@ func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Annotations: map[string]string{
-						// TODO: pass the checksum/config as a command line
-						"checksum/config": "75fffb14e7848a2319212c0422af0eb693157e9359a7cd10b59518125ad9822a",
+						"checksum/config": util.Checksum([]byte(nginxConfig)),
 					},
 					Labels: map[string]string{
 						"app": "nginx-server",
@@ -170,113 +263,31 @@ func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client,
 					Containers: []corev1.Container{
 						{
 							Name:            "nginx-server",
-							Image:           "nginx:1.23.1",
+							Image:           util.Image(v1alpha2.Image{}, defaultNginxImageRepository, defaultNginxImageTag, cfg.Registry),
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Command:         []string{"/bin/bash", "-c"},
 							Args:            []string{"nginx -g 'daemon off;'"},
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: int32(67),
-									Name:          "boots-dhcp",
-									Protocol:      corev1.ProtocolUDP,
-								},
-								{
-									ContainerPort: int32(80),
-									Name:          "boots-http",
-									Protocol:      corev1.ProtocolTCP,
-								},
-								{
-									ContainerPort: int32(69),
-									Name:          "boots-tftp",
-									Protocol:      corev1.ProtocolUDP,
-								},
-								{
-									ContainerPort: int32(514),
-									Name:          "boots-syslog",
-									Protocol:      corev1.ProtocolUDP,
-								},
-								{
-									ContainerPort: int32(50061),
-									Name:          "hegel-http",
-									Protocol:      corev1.ProtocolTCP,
-								},
-								{
-									ContainerPort: int32(42113),
-									Name:          "tink-grpc",
-									Protocol:      corev1.ProtocolTCP,
-								},
-								{
-									ContainerPort: int32(8080),
-									Name:          "hook-http",
-									Protocol:      corev1.ProtocolTCP,
-								},
-							},
-							Resources: corev1.ResourceRequirements{
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("64Mi"),
-									corev1.ResourceCPU:    resource.MustParse("10m"),
-								},
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("128Mi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
+							Ports:           nginxContainerPorts(stack),
+							Resources:       resources.ContainerResources(settings.Resources),
 							VolumeMounts: []corev1.VolumeMount{
 								{
-									MountPath: "/usr/share/nginx/html",
-									Name:      "hook-artifacts",
+									MountPath: hookArtifactsDir,
+									Name:      hookArtifactsVolumeName,
+									ReadOnly:  true,
 								},
 								{
-									MountPath: "/tmp",
+									MountPath: nginxConfigPath,
+									SubPath:   "nginx.conf",
 									ReadOnly:  true,
 									Name:      "nginx-conf",
 								},
 							},
 						},
 					},
-					InitContainers: []corev1.Container{
-						{
-							Name:    "init-hook-download",
-							Image:   "alpine",
-							Command: []string{"/bin/sh", "-xc"},
-							Args: []string{
-								"rm -rf /usr/share/nginx/html/checksums.txt;",
-								"touch /usr/share/nginx/html/checksums.txt;",
-								"echo \"7c35042d35c003ae1f424e503ad6edf21854bc70b24b37006e810c3c8a92543420eed129c14e364769b0f32c27bdf4c61299fce8f8156af7477cac6a43931a20  vmlinuz-x86_64\" >> /usr/share/nginx/html/checksums.txt;",
-								"echo \"be7c3d57e2d73bfa4e41a2b5740c722b1c83722e4388b3cff9017192fce43ede360221e3095c800e511d7b4bce6065f2906883421409dd6d983412418a8d903e  initramfs-x86_64\" >> /usr/share/nginx/html/checksums.txt;",
-								"echo \"2f1bdbf64380e281288f54c6ddd29221d8a007d29b40f405da0592ed32ef6e52695fc5071e05b2db3f075122943d62a2c266704d154a16ffb7b278c70538e7da  vmlinuz-aarch64\" >> /usr/share/nginx/html/checksums.txt;",
-								"echo \"5adc51798c8699f5f257599aabb999e2c2f65a07c9f8607c65510e57122b3e5c53196819e7ececdcda7b8fef47ba597ea7c4b53f2f4a92e236b20e355443eefe  initramfs-aarch64\" >> /usr/share/nginx/html/checksums.txt;",
-								"cd /usr/share/nginx/html/",
-								"sha512sum -c, checksums.txt && exit 0",
-								"apk add wget",
-								"echo downloading HOOK...",
-								"wget -O /tmp/hook0.tar.gz https://github.com/tinkerbell/hook/releases/download/v0.7.0/hook_x86_64.tar.gz;",
-								"tar -zxvf /tmp/hook0.tar.gz -C \"/usr/share/nginx/html/\"",
-								"rm -rf /tmp/hook0.tar.gz",
-								"apk add wget",
-								"echo downloading HOOK...",
-								"wget -O /tmp/hook1.tar.gz https://github.com/tinkerbell/hook/releases/download/v0.7.0/hook_aarch64.tar.gz;",
-								"tar -zxvf /tmp/hook1.tar.gz -C \"/usr/share/nginx/html/\"",
-								"rm -rf /tmp/hook1.tar.gz",
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									MountPath: "/usr/share/nginx/html",
-									Name:      "hook-artifacts",
-								},
-							},
-						},
-					},
+					InitContainers:   initContainers,
+					ImagePullSecrets: cfg.ImagePullSecrets,
 					Volumes: []corev1.Volume{
-						{
-							Name: "hook-artifacts",
-							VolumeSource: corev1.VolumeSource{
-								HostPath: &corev1.HostPathVolumeSource{
-									Path: "/opt/hook",
-									Type: &hostPathType,
-								},
-							},
-						},
+						hookArtifactsVolume(storage),
 						{
 							Name: "nginx-conf",
 							VolumeSource: corev1.VolumeSource{
@@ -287,7 +298,7 @@ func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client,
 									Items: []corev1.KeyToPath{
 										{
 											Key:  "nginx.conf",
-											Path: "nginx.conf.template",
+											Path: "nginx.conf",
 										},
 									},
 								},
@@ -299,13 +310,64 @@ func CreateNginxDeployment(ctx context.Context, client ctrlruntimeclient.Client,
 		},
 	}
 
-	if err := client.Create(ctx, deployment); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	deployment.Spec.Template.Spec.Volumes = append(deployment.Spec.Template.Spec.Volumes, hookVolumes...)
 
-		return err
+	resources.ApplyComponentDeployment(deployment, settings)
+	util.SetStackLabels(deployment, stack, NginxComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, deployment, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, deployment)
+}
+
+func nginxContainerPorts(stack *v1alpha2.Stack) []corev1.ContainerPort {
+	var ports []corev1.ContainerPort
+	if smee := stack.Spec.Services.Smee; smee != nil {
+		smeePorts := boots.ListenPorts(smee)
+		ports = append(ports,
+			corev1.ContainerPort{
+				ContainerPort: int32(smeePorts.DHCP),
+				Name:          "boots-dhcp",
+				Protocol:      corev1.ProtocolUDP,
+			},
+			corev1.ContainerPort{
+				ContainerPort: int32(smeePorts.HTTP),
+				Name:          "boots-http",
+				Protocol:      corev1.ProtocolTCP,
+			},
+			corev1.ContainerPort{
+				ContainerPort: int32(smeePorts.TFTP),
+				Name:          "boots-tftp",
+				Protocol:      corev1.ProtocolUDP,
+			},
+			corev1.ContainerPort{
+				ContainerPort: int32(smeePorts.Syslog),
+				Name:          "boots-syslog",
+				Protocol:      corev1.ProtocolUDP,
+			},
+		)
+	}
+
+	if stack.Spec.Services.Hegel != nil {
+		ports = append(ports, corev1.ContainerPort{
+			ContainerPort: int32(resources.HegelPort(stack)),
+			Name:          "hegel-http",
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+
+	return append(ports,
+		corev1.ContainerPort{
+			ContainerPort: int32(resources.TinkServerPort(stack)),
+			Name:          "tink-grpc",
+			Protocol:      corev1.ProtocolTCP,
+		},
+		corev1.ContainerPort{
+			ContainerPort: int32(resources.HookHTTPPort),
+			Name:          "hook-http",
+			Protocol:      corev1.ProtocolTCP,
+		},
+	)
 }
@@ -0,0 +1,302 @@
+package tink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ptr "k8s.io/utils/pointer"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// HookComponentName is the name of the Hook artifacts component in the Stack.
+	HookComponentName = "hook"
+	// HookDownloadJobPrefix is the name prefix of the Jobs publishing the Hook artifacts.
+	HookDownloadJobPrefix = "hook-download-"
+	// HookArtifactsPVCName is the name of the PersistentVolumeClaim storing the Hook artifacts.
+	HookArtifactsPVCName = "hook-artifacts"
+
+	hookArtifactsVolumeName = "hook-artifacts"
+	hookSourceVolumeName    = "hook-source"
+	hookToolsVolumeName     = "hook-tools"
+	hookToolsDir            = "/hook-tools"
+	hookArtifactsDir        = "/usr/share/nginx/html"
+	defaultHookHostPath     = "/opt/hook"
+)
+
+var (
+	hostPathType = corev1.HostPathDirectoryOrCreate
+
+	defaultHookPVCSize = resource.MustParse("2Gi")
+)
+
+// HookStorage returns the storage of the Hook artifacts of the given stack with the defaults applied.
+func HookStorage(stack *v1alpha2.Stack) v1alpha2.HookStorage {
+	storage := v1alpha2.HookStorage{}
+	if stack.Spec.HookArtifacts != nil && stack.Spec.HookArtifacts.Storage != nil {
+		storage = *stack.Spec.HookArtifacts.Storage.DeepCopy()
+	}
+
+	if storage.Type == "" {
+		storage.Type = v1alpha2.HookStorageTypeHostPath
+	}
+
+	switch storage.Type {
+	case v1alpha2.HookStorageTypePersistentVolumeClaim:
+		if storage.PersistentVolumeClaim == nil {
+			storage.PersistentVolumeClaim = &v1alpha2.HookPersistentVolumeClaim{}
+		}
+		if storage.PersistentVolumeClaim.Size == nil {
+			size := defaultHookPVCSize.DeepCopy()
+			storage.PersistentVolumeClaim.Size = &size
+		}
+		if storage.PersistentVolumeClaim.AccessMode == "" {
+			storage.PersistentVolumeClaim.AccessMode = corev1.ReadWriteOnce
+		}
+	case v1alpha2.HookStorageTypeHostPath:
+		if storage.HostPath == nil {
+			storage.HostPath = &v1alpha2.HookHostPath{}
+		}
+		if storage.HostPath.Path == "" {
+			storage.HostPath.Path = defaultHookHostPath
+		}
+	}
+
+	return storage
+}
+
+// HookStorageNodeLocal returns whether the Hook artifacts storage is only accessible from the node the nginx proxy is
+// running on, so that the artifacts have to be published from that node.
+func HookStorageNodeLocal(storage v1alpha2.HookStorage) bool {
+	switch storage.Type {
+	case v1alpha2.HookStorageTypePersistentVolumeClaim:
+		return storage.PersistentVolumeClaim.AccessMode != corev1.ReadWriteMany
+	case v1alpha2.HookStorageTypeHostPath:
+		return true
+	default:
+		return false
+	}
+}
+
+// hookArtifactsVolume is the volume the Hook artifacts are published into and served from.
+func hookArtifactsVolume(storage v1alpha2.HookStorage) corev1.Volume {
+	volume := corev1.Volume{Name: hookArtifactsVolumeName}
+
+	switch storage.Type {
+	case v1alpha2.HookStorageTypePersistentVolumeClaim:
+		volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+			ClaimName: HookArtifactsPVCName,
+		}
+	case v1alpha2.HookStorageTypeEmptyDir:
+		volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+	default:
+		volume.HostPath = &corev1.HostPathVolumeSource{
+			Path: storage.HostPath.Path,
+			Type: &hostPathType,
+		}
+	}
+
+	return volume
+}
+
+// hookDownloadContainers returns the containers which publish the given Hook archives into the artifacts volume, in
+// the order they have to run, along with the volumes they need besides the artifacts volume. The last container is the
+// downloader, the ones before prepare it.
+func hookDownloadContainers(stack *v1alpha2.Stack, cfg resources.Config, image string, archives []hook.Archive) ([]corev1.Container, []corev1.Volume, error) {
+	archivesJSON, err := json.Marshal(archives)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal hook archives: %w", err)
+	}
+
+	if cfg.Registry != "" {
+		image = util.OverwriteRegistry(image, cfg.Registry)
+	}
+
+	downloader := corev1.Container{
+		Name:            "hook-download",
+		Image:           image,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         []string{"tinkerbell", hook.DownloadCommand},
+		Args:            []string{"--dir", hookArtifactsDir, "--archives", string(archivesJSON)},
+		// The downloader writes into volumes owned by root, e.g. the host path.
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser: ptr.Int64(0),
+		},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		Resources:                hookDownloadResources(stack),
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				MountPath: hookArtifactsDir,
+				Name:      hookArtifactsVolumeName,
+			},
+		},
+	}
+
+	var source *v1alpha2.HookArtifactsSource
+	if stack.Spec.HookArtifacts != nil {
+		source = stack.Spec.HookArtifacts.Source
+	}
+
+	sourceMount := corev1.VolumeMount{
+		MountPath: hook.SourceDir,
+		Name:      hookSourceVolumeName,
+		ReadOnly:  true,
+	}
+
+	switch {
+	case source != nil && source.PersistentVolumeClaim != nil:
+		downloader.VolumeMounts = append(downloader.VolumeMounts, sourceMount)
+
+		return []corev1.Container{downloader}, []corev1.Volume{
+			{
+				Name: hookSourceVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: source.PersistentVolumeClaim.ClaimName,
+						ReadOnly:  true,
+					},
+				},
+			},
+		}, nil
+	case source != nil && source.Image != nil:
+		// The source image only contains the archives, so the downloader binary of the operator image is installed
+		// into a shared volume and run from the source image, which sees the archives at their path in the image.
+		toolsMount := corev1.VolumeMount{
+			MountPath: hookToolsDir,
+			Name:      hookToolsVolumeName,
+		}
+		binary := hookToolsDir + "/tinkerbell"
+
+		install := *downloader.DeepCopy()
+		install.Name = "hook-install-downloader"
+		install.Args = []string{"--install-to", binary}
+		install.VolumeMounts = []corev1.VolumeMount{toolsMount}
+
+		sourceImage := source.Image.Image
+		if cfg.Registry != "" {
+			sourceImage = util.OverwriteRegistry(sourceImage, cfg.Registry)
+		}
+
+		downloader.Image = sourceImage
+		downloader.Command = []string{binary, hook.DownloadCommand}
+		downloader.Args = []string{"--dir", hookArtifactsDir, "--archives", string(archivesJSON)}
+		downloader.VolumeMounts = append(downloader.VolumeMounts, toolsMount)
+
+		return []corev1.Container{install, downloader}, []corev1.Volume{
+			{
+				Name: hookToolsVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+		}, nil
+	default:
+		return []corev1.Container{downloader}, nil, nil
+	}
+}
+
+// HookDownloadSettings returns the deployment settings of the Hook download of the given stack.
+func HookDownloadSettings(stack *v1alpha2.Stack) v1alpha2.ComponentDeployment {
+	if stack.Spec.HookArtifacts == nil || stack.Spec.HookArtifacts.Download == nil {
+		return v1alpha2.ComponentDeployment{}
+	}
+
+	return *stack.Spec.HookArtifacts.Download
+}
+
+func hookDownloadResources(stack *v1alpha2.Stack) corev1.ResourceRequirements {
+	if settings := HookDownloadSettings(stack); settings.Resources != nil {
+		return *settings.Resources.DeepCopy()
+	}
+
+	return resources.DefaultHookDownloadResources()
+}
+
+// CreateHookArtifactsPVC creates the PersistentVolumeClaim the Hook artifacts are stored in.
+func CreateHookArtifactsPVC(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config) error {
+	claim := HookStorage(stack).PersistentVolumeClaim
+	if claim == nil {
+		return fmt.Errorf("hook artifacts storage is not of type %s", v1alpha2.HookStorageTypePersistentVolumeClaim)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      HookArtifactsPVCName,
+			Namespace: cfg.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{claim.AccessMode},
+			StorageClassName: claim.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: *claim.Size,
+				},
+			},
+		},
+	}
+
+	util.SetStackLabels(pvc, stack, HookComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, pvc, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, pvc)
+}
+
+// CreateHookDownloadJob creates a Job which publishes the given Hook archives into the artifacts volume of the nginx
+// proxy. If the node is set, the Job runs on that node, which is required by node local storage. The Job runs the
+// downloader of the operator image.
+func CreateHookDownloadJob(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, cfg resources.Config, name, node, image string, archives []hook.Archive) error {
+	containers, volumes, err := hookDownloadContainers(stack, cfg, image, archives)
+	if err != nil {
+		return err
+	}
+
+	var nodeSelector map[string]string
+	if node != "" {
+		nodeSelector = map[string]string{
+			corev1.LabelHostname: node,
+		}
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cfg.Namespace,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: ptr.Int32(3),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:    corev1.RestartPolicyNever,
+					NodeSelector:     nodeSelector,
+					InitContainers:   containers[:len(containers)-1],
+					Containers:       containers[len(containers)-1:],
+					ImagePullSecrets: cfg.ImagePullSecrets,
+					Volumes:          append([]corev1.Volume{hookArtifactsVolume(HookStorage(stack))}, volumes...),
+				},
+			},
+		},
+	}
+
+	resources.ApplyComponentJob(job, HookDownloadSettings(stack))
+	util.SetStackLabels(job, stack, HookComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, job, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, job)
+}
@@ -0,0 +1,133 @@
+package tink
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const testDownloaderImage = "quay.io/tinkerbell/operator:v0.1.0"
+
+// volumeMountFor returns the mount of the container the given path is in.
+func volumeMountFor(container corev1.Container, path string) (corev1.VolumeMount, bool) {
+	for _, mount := range container.VolumeMounts {
+		if path == mount.MountPath || strings.HasPrefix(path, strings.TrimSuffix(mount.MountPath, "/")+"/") {
+			return mount, true
+		}
+	}
+
+	return corev1.VolumeMount{}, false
+}
+
+func TestHookDownloadContainers(t *testing.T) {
+	rel, err := release.Get("v0.8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		source *v1alpha2.HookArtifactsSource
+		// expectedImage is the image the downloader runs from.
+		expectedImage string
+		// expectedClaim is the claim the archives are read from, if any.
+		expectedClaim string
+		expectedURL   string
+	}{
+		"no source": {
+			expectedImage: testDownloaderImage,
+			expectedURL:   "https://github.com/tinkerbell/hook/releases/download/" + rel.Hook.Version + "/hook_x86_64.tar.gz",
+		},
+		"image source": {
+			source:        &v1alpha2.HookArtifactsSource{Image: &v1alpha2.HookImageSource{Image: "registry.local/hook-bundle:v0.8.0"}},
+			expectedImage: "registry.local/hook-bundle:v0.8.0",
+			expectedURL:   "file:///hook/hook_x86_64.tar.gz",
+		},
+		"image source with a path": {
+			source: &v1alpha2.HookArtifactsSource{
+				Image: &v1alpha2.HookImageSource{Image: "registry.local/hook-bundle:v0.8.0", Path: "/srv/hook"},
+			},
+			expectedImage: "registry.local/hook-bundle:v0.8.0",
+			expectedURL:   "file:///srv/hook/hook_x86_64.tar.gz",
+		},
+		"claim source": {
+			source:        &v1alpha2.HookArtifactsSource{PersistentVolumeClaim: &v1alpha2.HookVolumeSource{ClaimName: "hook", Path: "v0.8.0"}},
+			expectedImage: testDownloaderImage,
+			expectedClaim: "hook",
+			expectedURL:   "file://" + hook.SourceDir + "/v0.8.0/hook_x86_64.tar.gz",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			stack := &v1alpha2.Stack{
+				Spec: v1alpha2.StackSpec{
+					HookArtifacts: &v1alpha2.HookArtifacts{Architectures: []string{"x86_64"}, Source: test.source},
+				},
+			}
+
+			_, archives, err := hook.Archives(stack.Spec.HookArtifacts, rel.Hook)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(archives) != 1 || archives[0].URL != test.expectedURL {
+				t.Fatalf("expected a single archive at %q, got %+v", test.expectedURL, archives)
+			}
+
+			containers, volumes, err := hookDownloadContainers(stack, resources.Config{Namespace: "tinkerbell"}, testDownloaderImage, archives)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			downloader := containers[len(containers)-1]
+			if downloader.Image != test.expectedImage {
+				t.Errorf("expected the downloader to run from %q, got %q", test.expectedImage, downloader.Image)
+			}
+
+			u, err := url.Parse(archives[0].URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if u.Scheme != "file" {
+				if test.expectedClaim != "" {
+					t.Fatalf("expected the archive to be read from the claim %q, got %q", test.expectedClaim, archives[0].URL)
+				}
+				return
+			}
+
+			mount, mounted := volumeMountFor(downloader, u.Path)
+			if test.expectedClaim == "" {
+				// The archives of an image source are read from the image itself, so no volume may hide them.
+				if mounted {
+					t.Fatalf("expected the archive %q to be read from the source image, but it is in the volume %q", u.Path, mount.Name)
+				}
+				return
+			}
+
+			if !mounted {
+				t.Fatalf("expected the archive %q to be in a volume of the downloader, got the mounts %+v", u.Path, downloader.VolumeMounts)
+			}
+
+			for _, volume := range volumes {
+				if volume.Name != mount.Name {
+					continue
+				}
+
+				if volume.PersistentVolumeClaim == nil || volume.PersistentVolumeClaim.ClaimName != test.expectedClaim {
+					t.Fatalf("expected the volume %q to be the claim %q, got %+v", volume.Name, test.expectedClaim, volume.VolumeSource)
+				}
+				return
+			}
+
+			t.Fatalf("expected a volume %q for the mount of the archive", mount.Name)
+		})
+	}
+}
@@ -2,11 +2,15 @@ package tink
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/util"
 
 	rbacv1 "k8s.io/api/rbac/v1"
-	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -20,10 +24,10 @@ const (
 	roleBinding = "tink-leader-election-role-binding"
 )
 
-func CreateTinkControllerClusterRole(ctx context.Context, client ctrlruntimeclient.Client) error {
+func CreateTinkControllerClusterRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack) error {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: v1.ObjectMeta{
-			Name: tinkControllerClusterRole,
+			Name: util.ClusterScopedName(stack, tinkControllerClusterRole),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -44,21 +48,15 @@ func CreateTinkControllerClusterRole(ctx context.Context, client ctrlruntimeclie
 		},
 	}
 
-	if err := client.Create(ctx, clusterRole); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRole, stack, TinkControllerComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRole)
 }
 
-func CreateTinkServerClusterRole(ctx context.Context, client ctrlruntimeclient.Client) error {
+func CreateTinkServerClusterRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack) error {
 	clusterRole := &rbacv1.ClusterRole{
 		ObjectMeta: v1.ObjectMeta{
-			Name: tinkServerClusterRole,
+			Name: util.ClusterScopedName(stack, tinkServerClusterRole),
 		},
 		Rules: []rbacv1.PolicyRule{
 			{
@@ -79,21 +77,15 @@ func CreateTinkServerClusterRole(ctx context.Context, client ctrlruntimeclient.C
 		},
 	}
 
-	if err := client.Create(ctx, clusterRole); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRole, stack, TinkServerComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRole)
 }
 
-func CreateTinkControllerClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateTinkControllerClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: v1.ObjectMeta{
-			Name: tinkControllerClusterRoleBinding,
+			Name: util.ClusterScopedName(stack, tinkControllerClusterRoleBinding),
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -105,25 +97,19 @@ func CreateTinkControllerClusterRoleBinding(ctx context.Context, client ctrlrunt
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
 			Kind:     "ClusterRole",
-			Name:     tinkControllerClusterRole,
+			Name:     util.ClusterScopedName(stack, tinkControllerClusterRole),
 		},
 	}
 
-	if err := client.Create(ctx, clusterRoleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(clusterRoleBinding, stack, TinkControllerComponentName)
 
-		return err
-	}
-
-	return nil
+	return util.Apply(ctx, client, clusterRoleBinding)
 }
 
-func CreateTinkServerClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateTinkServerClusterRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
 		ObjectMeta: v1.ObjectMeta{
-			Name: tinkServerClusterRoleBinding,
+			Name: util.ClusterScopedName(stack, tinkServerClusterRoleBinding),
 		},
 		Subjects: []rbacv1.Subject{
 			{
@@ -135,22 +121,16 @@ func CreateTinkServerClusterRoleBinding(ctx context.Context, client ctrlruntimec
 		RoleRef: rbacv1.RoleRef{
 			APIGroup: rbacv1.GroupName,
 			Kind:     "ClusterRole",
-			Name:     tinkServerClusterRole,
+			Name:     util.ClusterScopedName(stack, tinkServerClusterRole),
 		},
 	}
 
-	if err := client.Create(ctx, clusterRoleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
-
-		return err
-	}
+	util.SetStackLabels(clusterRoleBinding, stack, TinkServerComponentName)
 
-	return nil
+	return util.Apply(ctx, client, clusterRoleBinding)
 }
 
-func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	role := &rbacv1.Role{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      role,
@@ -175,18 +155,16 @@ func CreateRole(ctx context.Context, client ctrlruntimeclient.Client, ns string)
 		},
 	}
 
-	if err := client.Create(ctx, role); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(role, stack, TinkControllerComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, role, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, role)
 }
 
-func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns string) error {
+func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string) error {
 	roleBinding := &rbacv1.RoleBinding{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      roleBinding,
@@ -206,13 +184,11 @@ func CreateRoleBinding(ctx context.Context, client ctrlruntimeclient.Client, ns
 		},
 	}
 
-	if err := client.Create(ctx, roleBinding); err != nil {
-		if kerrors.IsAlreadyExists(err) {
-			return nil
-		}
+	util.SetStackLabels(roleBinding, stack, TinkControllerComponentName)
 
-		return err
+	if err := controllerutil.SetControllerReference(stack, roleBinding, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
 	}
 
-	return nil
+	return util.Apply(ctx, client, roleBinding)
 }
@@ -0,0 +1,142 @@
+package tink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/pki"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/util"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// TinkServerCASecretName is the name of the secret containing the CA that signs the tink-server certificate.
+	TinkServerCASecretName = "tink-server-ca"
+	// TinkServerTLSSecretName is the name of the secret containing the tink-server serving certificate.
+	TinkServerTLSSecretName = "tink-server-tls"
+	// TinkServerCertificateName is the name of the cert-manager Certificate of tink-server.
+	TinkServerCertificateName = "tink-server"
+
+	// CACertKey is the key of the CA certificate in the TLS secrets, it matches the key cert-manager uses.
+	CACertKey = "ca.crt"
+	// CAKeyKey is the key of the CA private key in the CA secret.
+	CAKeyKey = "ca.key"
+
+	tinkServerCertsDir = "/certs"
+)
+
+// CertificateGVK is the GroupVersionKind of the cert-manager Certificate.
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// TinkServerDNSNames returns the DNS names of the tink-server Service which the serving certificate has to cover.
+func TinkServerDNSNames(cfg resources.Config) []string {
+	return []string{
+		TinkServerServiceName,
+		fmt.Sprintf("%s.%s", TinkServerServiceName, cfg.Namespace),
+		fmt.Sprintf("%s.%s.svc", TinkServerServiceName, cfg.Namespace),
+		resources.ServiceHost(TinkServerServiceName, cfg),
+	}
+}
+
+func CreateTinkServerCASecret(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string, ca pki.KeyPair) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TinkServerCASecretName,
+			Namespace: ns,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			CACertKey: ca.Cert,
+			CAKeyKey:  ca.Key,
+		},
+	}
+
+	util.SetStackLabels(secret, stack, TinkServerComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, secret, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, secret)
+}
+
+func CreateTinkServerTLSSecret(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string, serving pki.KeyPair, caCert []byte) error {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      TinkServerTLSSecretName,
+			Namespace: ns,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       serving.Cert,
+			corev1.TLSPrivateKeyKey: serving.Key,
+			CACertKey:               caCert,
+		},
+	}
+
+	util.SetStackLabels(secret, stack, TinkServerComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, secret, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, secret)
+}
+
+// CreateTinkServerCertificate creates a cert-manager Certificate which issues the tink-server serving certificate into
+// the tink-server TLS secret. The Certificate is handled as unstructured object to not depend on the cert-manager API.
+func CreateTinkServerCertificate(ctx context.Context, client ctrlruntimeclient.Client, stack *v1alpha2.Stack, ns string, dnsNames, ips []string, issuer v1alpha2.CertManagerIssuerRef) error {
+	issuerRef := map[string]interface{}{
+		"name": issuer.Name,
+	}
+	if issuer.Kind != "" {
+		issuerRef["kind"] = issuer.Kind
+	}
+	if issuer.Group != "" {
+		issuerRef["group"] = issuer.Group
+	}
+
+	spec := map[string]interface{}{
+		"secretName":  TinkServerTLSSecretName,
+		"commonName":  TinkServerDeploymentName,
+		"dnsNames":    toInterfaces(dnsNames),
+		"usages":      []interface{}{"server auth", "digital signature", "key encipherment"},
+		"issuerRef":   issuerRef,
+		"duration":    pki.ServingValidity.String(),
+		"renewBefore": pki.RenewBefore.String(),
+	}
+	if len(ips) > 0 {
+		spec["ipAddresses"] = toInterfaces(ips)
+	}
+
+	certificate := &unstructured.Unstructured{}
+	certificate.SetGroupVersionKind(CertificateGVK)
+	certificate.SetName(TinkServerCertificateName)
+	certificate.SetNamespace(ns)
+	certificate.Object["spec"] = spec
+
+	util.SetStackLabels(certificate, stack, TinkServerComponentName)
+
+	if err := controllerutil.SetControllerReference(stack, certificate, client.Scheme()); err != nil {
+		return fmt.Errorf("failed to set owner reference: %w", err)
+	}
+
+	return util.Apply(ctx, client, certificate)
+}
+
+func toInterfaces(values []string) []interface{} {
+	out := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		out = append(out, v)
+	}
+
+	return out
+}
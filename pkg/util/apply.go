@@ -0,0 +1,31 @@
+package util
+
+import (
+	"context"
+	"fmt"
+
+	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// FieldManager is the field manager the operator uses when applying the tinkerbell resources.
+const FieldManager = "tinkerbell-operator"
+
+// Apply creates or updates the given object using server-side apply. The operator forces the ownership of the fields
+// it sets, so any manual change on those fields is reverted to the desired state.
+func Apply(ctx context.Context, client ctrlruntimeclient.Client, obj ctrlruntimeclient.Object) error {
+	// Server-side apply requires the apiVersion and kind to be part of the request body.
+	gvk, err := apiutil.GVKForObject(obj, client.Scheme())
+	if err != nil {
+		return fmt.Errorf("failed to get group version kind for %T: %w", obj, err)
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+	obj.SetResourceVersion("")
+
+	if err := client.Patch(ctx, obj, ctrlruntimeclient.Apply, ctrlruntimeclient.FieldOwner(FieldManager), ctrlruntimeclient.ForceOwnership); err != nil {
+		return fmt.Errorf("failed to apply %s %q: %w", gvk.Kind, obj.GetName(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Checksum returns the sha256 checksum of the given data. Stamped on a pod template, it rolls out the pods whenever the
+// configuration they consume changes.
+func Checksum(data ...[]byte) string {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,53 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+)
+
+const defaultRegistry = "docker.io"
+
+// Image returns the image reference for a tinkerbell service. The repository and tag from the given image spec take
+// precedence over the defaults. If registry is set, it replaces the registry of the resulting image.
+func Image(image v1alpha2.Image, defaultRepository, defaultTag, registry string) string {
+	repository := defaultRepository
+	if image.Repository != "" {
+		repository = image.Repository
+	}
+
+	tag := defaultTag
+	if image.Tag != "" {
+		tag = image.Tag
+	}
+
+	if registry != "" {
+		repository = OverwriteRegistry(repository, registry)
+	}
+
+	return fmt.Sprintf("%s:%s", repository, tag)
+}
+
+// OverwriteRegistry replaces the registry of the given repository, e.g. quay.io/tinkerbell/smee becomes
+// registry.local/tinkerbell/smee and nginx becomes registry.local/library/nginx.
+func OverwriteRegistry(repository, registry string) string {
+	_, path := splitRepository(repository)
+
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(registry, "/"), path)
+}
+
+// splitRepository splits the given repository into its registry and path, following the same rules as docker: the
+// first path component is only a registry if it looks like a hostname.
+func splitRepository(repository string) (string, string) {
+	i := strings.Index(repository, "/")
+	if i == -1 {
+		return defaultRegistry, "library/" + repository
+	}
+
+	if domain := repository[:i]; strings.ContainsAny(domain, ".:") || domain == "localhost" {
+		return domain, repository[i+1:]
+	}
+
+	return defaultRegistry, repository
+}
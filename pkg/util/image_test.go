@@ -0,0 +1,111 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+)
+
+func TestOverwriteRegistry(t *testing.T) {
+	tests := map[string]struct {
+		repository string
+		registry   string
+		expected   string
+	}{
+		"registry with a domain": {
+			repository: "quay.io/tinkerbell/smee",
+			registry:   "registry.local",
+			expected:   "registry.local/tinkerbell/smee",
+		},
+		"registry with a port": {
+			repository: "localhost:5000/tinkerbell/smee",
+			registry:   "registry.local:5000",
+			expected:   "registry.local:5000/tinkerbell/smee",
+		},
+		"localhost registry": {
+			repository: "localhost/smee",
+			registry:   "registry.local",
+			expected:   "registry.local/smee",
+		},
+		"no registry": {
+			repository: "tinkerbell/smee",
+			registry:   "registry.local",
+			expected:   "registry.local/tinkerbell/smee",
+		},
+		"official image": {
+			repository: "nginx",
+			registry:   "registry.local",
+			expected:   "registry.local/library/nginx",
+		},
+		"official image with a tag": {
+			repository: "nginx:1.25.1",
+			registry:   "registry.local",
+			expected:   "registry.local/library/nginx:1.25.1",
+		},
+		"image with a tag": {
+			repository: "quay.io/tinkerbell/hook:v0.8.0",
+			registry:   "registry.local",
+			expected:   "registry.local/tinkerbell/hook:v0.8.0",
+		},
+		"image with a digest": {
+			repository: "quay.io/tinkerbell/hook@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			registry:   "registry.local",
+			expected:   "registry.local/tinkerbell/hook@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		"registry with a port and a digest": {
+			repository: "localhost:5000/hook:v0.8.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			registry:   "registry.local:5000",
+			expected:   "registry.local:5000/hook:v0.8.0@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		},
+		"registry with a path": {
+			repository: "quay.io/tinkerbell/smee",
+			registry:   "registry.local:5000/mirror/",
+			expected:   "registry.local:5000/mirror/tinkerbell/smee",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if image := OverwriteRegistry(test.repository, test.registry); image != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, image)
+			}
+		})
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := map[string]struct {
+		image    v1alpha2.Image
+		registry string
+		expected string
+	}{
+		"defaults": {
+			expected: "quay.io/tinkerbell/smee:v0.8.0",
+		},
+		"custom repository": {
+			image:    v1alpha2.Image{Repository: "registry.local/smee"},
+			expected: "registry.local/smee:v0.8.0",
+		},
+		"custom tag": {
+			image:    v1alpha2.Image{Tag: "latest"},
+			expected: "quay.io/tinkerbell/smee:latest",
+		},
+		"overwritten registry": {
+			registry: "registry.local:5000",
+			expected: "registry.local:5000/tinkerbell/smee:v0.8.0",
+		},
+		"overwritten registry of a custom repository": {
+			image:    v1alpha2.Image{Repository: "smee", Tag: "latest"},
+			registry: "registry.local",
+			expected: "registry.local/library/smee:latest",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if image := Image(test.image, "quay.io/tinkerbell/smee", "v0.8.0", test.registry); image != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, image)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StackNameLabel is set on every object the operator creates and holds the name of the owning Stack.
+	StackNameLabel = "tinkerbell.org/stack"
+	// StackNamespaceLabel is set on every object the operator creates and holds the namespace of the owning Stack.
+	// It is required to track cluster-scoped objects, which can't carry an owner reference to a namespaced Stack.
+	StackNamespaceLabel = "tinkerbell.org/stack-namespace"
+	// ComponentLabel is set on every object the operator creates and holds the name of the stack component the object
+	// belongs to.
+	ComponentLabel = "tinkerbell.org/component"
+)
+
+// StackLabels returns the labels identifying the objects that belong to the given stack.
+func StackLabels(stack *v1alpha2.Stack) map[string]string {
+	return map[string]string{
+		StackNameLabel:      stack.Name,
+		StackNamespaceLabel: stack.Namespace,
+	}
+}
+
+// ComponentLabels returns the labels identifying the objects that belong to the given stack component.
+func ComponentLabels(stack *v1alpha2.Stack, component string) map[string]string {
+	labels := StackLabels(stack)
+	labels[ComponentLabel] = component
+
+	return labels
+}
+
+// SetStackLabels adds the stack and component labels to the given object, keeping the labels that are already set.
+func SetStackLabels(obj metav1.Object, stack *v1alpha2.Stack, component string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	for k, v := range ComponentLabels(stack, component) {
+		labels[k] = v
+	}
+
+	obj.SetLabels(labels)
+}
+
+// ClusterScopedName returns the name of a cluster-scoped object of the given stack. Cluster-scoped objects are shared
+// by all the stacks of the cluster, so their names carry the namespace and name of the stack.
+func ClusterScopedName(stack *v1alpha2.Stack, name string) string {
+	return fmt.Sprintf("%s-%s-%s", name, stack.Namespace, stack.Name)
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	ctrlruntimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -27,9 +29,65 @@ func Factory(filter func(o ctrlruntimeclient.Object) bool) predicate.Funcs {
 	}
 }
 
-// ByNamespace returns a predicate func that only includes objects in the given namespace.
+// ByNamespace returns a predicate func that only includes objects in the given namespace. An empty namespace includes
+// the objects of all namespaces.
 func ByNamespace(namespace string) predicate.Funcs {
 	return Factory(func(o ctrlruntimeclient.Object) bool {
-		return o.GetNamespace() == namespace
+		return namespace == "" || o.GetNamespace() == namespace
 	})
 }
+
+// NodeNetworkChanged returns a predicate func that includes node creations and deletions, and only the node updates
+// that change the node pod CIDRs or addresses.
+func NodeNetworkChanged() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return true
+			}
+
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return true
+			}
+
+			return oldNode.Spec.PodCIDR != newNode.Spec.PodCIDR ||
+				!equality.Semantic.DeepEqual(oldNode.Spec.PodCIDRs, newNode.Spec.PodCIDRs) ||
+				!equality.Semantic.DeepEqual(oldNode.Status.Addresses, newNode.Status.Addresses)
+		},
+	}
+}
+
+// PodPlacementChanged returns a predicate func that includes pod creations and deletions, and only the pod updates that
+// change the node the pod is scheduled on, its readiness or start its deletion.
+func PodPlacementChanged() predicate.Funcs {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldPod, ok := e.ObjectOld.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+
+			newPod, ok := e.ObjectNew.(*corev1.Pod)
+			if !ok {
+				return true
+			}
+
+			return oldPod.Spec.NodeName != newPod.Spec.NodeName ||
+				PodReady(oldPod) != PodReady(newPod) ||
+				oldPod.DeletionTimestamp.IsZero() != newPod.DeletionTimestamp.IsZero()
+		},
+	}
+}
+
+// PodReady returns whether the Ready condition of the given pod is true.
+func PodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
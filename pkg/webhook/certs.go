@@ -0,0 +1,364 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tinkerbell/operator/pkg/pki"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// CertSecretName is the secret the CA and the serving certificate of the webhook server are stored in.
+	CertSecretName = "tinkerbell-operator-webhook-cert"
+	// WebhookConfigurationName is the name of the validating and mutating webhook configurations the CA bundle is
+	// injected into.
+	WebhookConfigurationName = "tinkerbell-operator"
+	// StackCRDName is the name of the Stack CRD the CA bundle of the conversion webhook is injected into.
+	StackCRDName = "stack.tinkerbell.org"
+
+	caCertKey = "ca.crt"
+	caKeyKey  = "ca.key"
+	// previousCACertKey holds the renewed CA while the serving certificate moves to the new one.
+	previousCACertKey = "ca-previous.crt"
+
+	// rotationInterval is how often the certificates are checked for renewal.
+	rotationInterval = time.Hour
+	// previousCAGracePeriod is how long the previous CA stays trusted once the serving certificate is issued by the new
+	// one, so that every replica loads the new serving certificate before. The serving certificates are backdated by an
+	// hour.
+	previousCAGracePeriod = rotationInterval + time.Hour
+)
+
+// CertRotator issues the serving certificate of the webhook server from a CA of its own, renews both before they
+// expire and keeps the CA bundle of the webhook configurations and the Stack CRD conversion up to date. The certificate is written into the
+// directory the webhook server reloads it from.
+//
+// A renewed CA is rolled out over several passes: the CA bundle trusts the previous and the new CA before the serving
+// certificate is issued by the new CA, and the previous CA is dropped from the bundle once every replica serves the new
+// certificate.
+type CertRotator struct {
+	Client client.Client
+	Log    *zap.SugaredLogger
+
+	// Service is the Service the API server reaches the webhook server through. The secret is stored in its
+	// namespace.
+	Service       types.NamespacedName
+	ClusterDomain string
+	CertDir       string
+
+	// now returns the current time, it defaults to time.Now.
+	now func() time.Time
+}
+
+var _ manager.Runnable = &CertRotator{}
+
+// NeedLeaderElection returns false, every replica serves the webhooks and needs the certificate.
+func (c *CertRotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start renews the certificates periodically until the context is done.
+func (c *CertRotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(rotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := c.Ensure(ctx); err != nil {
+				c.Log.Errorf("failed to rotate webhook certificates: %v", err)
+			}
+		}
+	}
+}
+
+// Ensure makes sure a valid CA and serving certificate exist, writes them into the certificate directory and injects
+// the CA into the webhook configurations and the Stack CRD conversion. It must succeed once before the webhook server
+// is started.
+func (c *CertRotator) Ensure(ctx context.Context) error {
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: c.Service.Namespace, Name: CertSecretName}
+	if err := c.Client.Get(ctx, key, secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get secret %q: %w", key, err)
+		}
+		secret = nil
+	}
+
+	ca, serving := pki.KeyPair{}, pki.KeyPair{}
+	var previousCA []byte
+	if secret != nil {
+		ca = pki.KeyPair{Cert: secret.Data[caCertKey], Key: secret.Data[caKeyKey]}
+		serving = pki.KeyPair{Cert: secret.Data[corev1.TLSCertKey], Key: secret.Data[corev1.TLSPrivateKeyKey]}
+		previousCA = secret.Data[previousCACertKey]
+	}
+
+	renewed := false
+	if !pki.ValidCA(ca, now) {
+		c.Log.Info("Generating webhook CA..")
+
+		// The replicas keep serving the certificate of the previous CA until they load the one of the new CA.
+		previousCA = nil
+		if _, err := pki.ParseCert(ca.Cert); err == nil {
+			previousCA = ca.Cert
+		}
+
+		var err error
+		if ca, err = pki.NewCA("tinkerbell-operator-webhook-ca"); err != nil {
+			return fmt.Errorf("failed to generate webhook CA: %w", err)
+		}
+		renewed = true
+	}
+
+	dnsNames := c.dnsNames()
+	if len(previousCA) > 0 && pki.ValidServingCert(serving, ca, dnsNames, nil, now) && servingCertIssuedBefore(serving, now.Add(-previousCAGracePeriod)) {
+		c.Log.Info("Dropping the previous webhook CA from the CA bundle..")
+
+		previousCA = nil
+		renewed = true
+	}
+
+	caBundle := ca.Cert
+	if len(previousCA) > 0 {
+		caBundle = append(append([]byte{}, ca.Cert...), previousCA...)
+	}
+
+	injected, err := c.injectCABundle(ctx, caBundle)
+	if err != nil {
+		return err
+	}
+
+	conversionInjected, err := c.injectConversionCABundle(ctx, caBundle)
+	if err != nil {
+		return err
+	}
+
+	if !pki.ValidServingCert(serving, ca, dnsNames, nil, now) {
+		// The API server only trusts the new CA once the bundle injected by an earlier pass is in place, until then the
+		// certificate of the previous CA is served as long as it is valid.
+		if (injected || conversionInjected) && len(previousCA) > 0 && pki.ValidServingCert(serving, pki.KeyPair{Cert: previousCA}, dnsNames, nil, now) {
+			c.Log.Info("Waiting for the webhook CA bundle to be trusted before renewing the serving certificate..")
+		} else {
+			c.Log.Info("Generating webhook serving certificate..")
+
+			if serving, err = pki.NewServingCert(ca, dnsNames[0], dnsNames, nil); err != nil {
+				return fmt.Errorf("failed to generate webhook serving certificate: %w", err)
+			}
+			renewed = true
+		}
+	}
+
+	if secret == nil || renewed {
+		if err := c.storeSecret(ctx, secret, ca, previousCA, serving); err != nil {
+			return err
+		}
+	}
+
+	return c.writeCertDir(serving)
+}
+
+// servingCertIssuedBefore returns whether the given serving certificate is valid since before the given time.
+func servingCertIssuedBefore(serving pki.KeyPair, t time.Time) bool {
+	cert, err := pki.ParseCert(serving.Cert)
+	if err != nil {
+		return false
+	}
+
+	return cert.NotBefore.Before(t)
+}
+
+// storeSecret creates or updates the certificate secret. A conflict means another replica renewed the certificates
+// concurrently, the next Ensure picks up its certificates.
+func (c *CertRotator) storeSecret(ctx context.Context, secret *corev1.Secret, ca pki.KeyPair, previousCA []byte, serving pki.KeyPair) error {
+	data := map[string][]byte{
+		caCertKey:               ca.Cert,
+		caKeyKey:                ca.Key,
+		corev1.TLSCertKey:       serving.Cert,
+		corev1.TLSPrivateKeyKey: serving.Key,
+	}
+	if len(previousCA) > 0 {
+		data[previousCACertKey] = previousCA
+	}
+
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      CertSecretName,
+				Namespace: c.Service.Namespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		}
+
+		if err := c.Client.Create(ctx, secret); err != nil {
+			return fmt.Errorf("failed to create secret %q: %w", CertSecretName, err)
+		}
+
+		return nil
+	}
+
+	secret.Data = data
+	if err := c.Client.Update(ctx, secret); err != nil {
+		return fmt.Errorf("failed to update secret %q: %w", CertSecretName, err)
+	}
+
+	return nil
+}
+
+// writeCertDir writes the serving certificate into the certificate directory if it changed. The files are replaced
+// atomically, so that the webhook server never loads a certificate without its key.
+func (c *CertRotator) writeCertDir(serving pki.KeyPair) error {
+	certFile, keyFile := filepath.Join(c.CertDir, corev1.TLSCertKey), filepath.Join(c.CertDir, corev1.TLSPrivateKeyKey)
+
+	if current, err := os.ReadFile(certFile); err == nil && bytes.Equal(current, serving.Cert) {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.CertDir, 0o700); err != nil {
+		return fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+
+	// The key is written first, the webhook server reloads the pair once the certificate changes.
+	for _, f := range []struct {
+		path string
+		data []byte
+	}{{keyFile, serving.Key}, {certFile, serving.Cert}} {
+		tmp := f.path + ".tmp"
+		if err := os.WriteFile(tmp, f.data, 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", tmp, err)
+		}
+
+		if err := os.Rename(tmp, f.path); err != nil {
+			return fmt.Errorf("failed to rename %s: %w", tmp, err)
+		}
+	}
+
+	return nil
+}
+
+// injectCABundle sets the CA bundle of all the webhooks of the operator webhook configurations and returns whether any of
+// them changed. A missing configuration is skipped, e.g. when the operator runs outside of the cluster.
+func (c *CertRotator) injectCABundle(ctx context.Context, caBundle []byte) (bool, error) {
+	configs := []client.Object{
+		&admissionregistrationv1.ValidatingWebhookConfiguration{},
+		&admissionregistrationv1.MutatingWebhookConfiguration{},
+	}
+
+	injected := false
+	for _, config := range configs {
+		if err := c.Client.Get(ctx, types.NamespacedName{Name: WebhookConfigurationName}, config); err != nil {
+			if kerrors.IsNotFound(err) {
+				c.Log.Warnf("%T %q not found, skipping the CA bundle injection", config, WebhookConfigurationName)
+				continue
+			}
+
+			return false, fmt.Errorf("failed to get %T %q: %w", config, WebhookConfigurationName, err)
+		}
+
+		patch := client.MergeFrom(config.DeepCopyObject().(client.Object))
+
+		var clientConfigs []*admissionregistrationv1.WebhookClientConfig
+		switch config := config.(type) {
+		case *admissionregistrationv1.ValidatingWebhookConfiguration:
+			for i := range config.Webhooks {
+				clientConfigs = append(clientConfigs, &config.Webhooks[i].ClientConfig)
+			}
+		case *admissionregistrationv1.MutatingWebhookConfiguration:
+			for i := range config.Webhooks {
+				clientConfigs = append(clientConfigs, &config.Webhooks[i].ClientConfig)
+			}
+		}
+
+		changed := false
+		for _, clientConfig := range clientConfigs {
+			if !bytes.Equal(clientConfig.CABundle, caBundle) {
+				clientConfig.CABundle = caBundle
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := c.Client.Patch(ctx, config, patch); err != nil {
+			return false, fmt.Errorf("failed to patch %T %q: %w", config, WebhookConfigurationName, err)
+		}
+		injected = true
+	}
+
+	return injected, nil
+}
+
+// injectConversionCABundle injects the CA bundle into the conversion webhook of the Stack CRD, so that the API server
+// trusts the operator when converting the Stack versions. The conversion webhook itself is configured in the CRD
+// manifest, a missing CRD is skipped like a missing webhook configuration. It returns whether the CA bundle changed.
+func (c *CertRotator) injectConversionCABundle(ctx context.Context, caBundle []byte) (bool, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Client.Get(ctx, types.NamespacedName{Name: StackCRDName}, crd); err != nil {
+		if kerrors.IsNotFound(err) {
+			c.Log.Warnf("CRD %q not found, skipping the conversion webhook CA bundle injection", StackCRDName)
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get CRD %q: %w", StackCRDName, err)
+	}
+
+	conversion := crd.Spec.Conversion
+	if conversion == nil || conversion.Strategy != apiextensionsv1.WebhookConverter || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil {
+		c.Log.Warnf("CRD %q has no conversion webhook configured, skipping the CA bundle injection", StackCRDName)
+		return false, nil
+	}
+
+	if bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+		return false, nil
+	}
+
+	patch := client.MergeFrom(crd.DeepCopy())
+	conversion.Webhook.ClientConfig.CABundle = caBundle
+	if err := c.Client.Patch(ctx, crd, patch); err != nil {
+		return false, fmt.Errorf("failed to patch CRD %q: %w", StackCRDName, err)
+	}
+
+	return true, nil
+}
+
+// dnsNames returns the DNS names the API server may address the webhook Service with.
+func (c *CertRotator) dnsNames() []string {
+	name, namespace := c.Service.Name, c.Service.Namespace
+
+	names := []string{
+		fmt.Sprintf("%s.%s.svc", name, namespace),
+		name,
+		fmt.Sprintf("%s.%s", name, namespace),
+	}
+
+	if c.ClusterDomain != "" {
+		names = append(names, fmt.Sprintf("%s.%s.svc.%s", name, namespace, c.ClusterDomain))
+	}
+
+	return names
+}
@@ -0,0 +1,281 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tinkerbell/operator/pkg/pki"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const testOperatorNamespace = "tinkerbell-operator"
+
+// newTestCertRotator returns a rotator backed by a fake client holding the operator webhook configurations, the Stack
+// CRD and the given objects.
+func newTestCertRotator(t *testing.T, objs ...client.Object) *CertRotator {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	objs = append(objs,
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: WebhookConfigurationName},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "vstack.tinkerbell.org"}},
+		},
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: WebhookConfigurationName},
+			Webhooks:   []admissionregistrationv1.MutatingWebhook{{Name: "mstack.tinkerbell.org"}},
+		},
+		&apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: StackCRDName},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Conversion: &apiextensionsv1.CustomResourceConversion{
+					Strategy: apiextensionsv1.WebhookConverter,
+					Webhook:  &apiextensionsv1.WebhookConversion{ClientConfig: &apiextensionsv1.WebhookClientConfig{}},
+				},
+			},
+		},
+	)
+
+	return &CertRotator{
+		Client:  fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Log:     zap.NewNop().Sugar(),
+		Service: types.NamespacedName{Namespace: testOperatorNamespace, Name: "tinkerbell-operator-webhook"},
+		CertDir: t.TempDir(),
+	}
+}
+
+// newExpiringCA returns a CA which expires at the given time.
+func newExpiringCA(t *testing.T, notAfter time.Time) pki.KeyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tinkerbell-operator-webhook-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pki.KeyPair{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func certSecret(t *testing.T, c *CertRotator) *corev1.Secret {
+	t.Helper()
+
+	secret := &corev1.Secret{}
+	if err := c.Client.Get(context.Background(), types.NamespacedName{Namespace: testOperatorNamespace, Name: CertSecretName}, secret); err != nil {
+		t.Fatal(err)
+	}
+
+	return secret
+}
+
+// expectCABundle checks the CA bundle injected into the webhook configurations and the Stack CRD conversion.
+func expectCABundle(t *testing.T, c *CertRotator, expected []byte) {
+	t.Helper()
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: WebhookConfigurationName}
+
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := c.Client.Get(ctx, key, validating); err != nil {
+		t.Fatal(err)
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := c.Client.Get(ctx, key, mutating); err != nil {
+		t.Fatal(err)
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Client.Get(ctx, types.NamespacedName{Name: StackCRDName}, crd); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, caBundle := range map[string][]byte{
+		"validating webhook": validating.Webhooks[0].ClientConfig.CABundle,
+		"mutating webhook":   mutating.Webhooks[0].ClientConfig.CABundle,
+		"conversion webhook": crd.Spec.Conversion.Webhook.ClientConfig.CABundle,
+	} {
+		if !bytes.Equal(caBundle, expected) {
+			t.Errorf("unexpected CA bundle of the %s:\n%s\nexpected:\n%s", name, caBundle, expected)
+		}
+	}
+}
+
+// expectServingCert checks the serving certificate in the secret and in the certificate directory.
+func expectServingCert(t *testing.T, c *CertRotator, expected []byte) {
+	t.Helper()
+
+	if cert := certSecret(t, c).Data[corev1.TLSCertKey]; !bytes.Equal(cert, expected) {
+		t.Errorf("unexpected serving certificate in the secret:\n%s", cert)
+	}
+
+	cert, err := os.ReadFile(filepath.Join(c.CertDir, corev1.TLSCertKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(cert, expected) {
+		t.Errorf("unexpected serving certificate in the certificate directory:\n%s", cert)
+	}
+}
+
+func TestCertRotatorEnsure(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCertRotator(t)
+
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := certSecret(t, c)
+	ca := pki.KeyPair{Cert: secret.Data[caCertKey], Key: secret.Data[caKeyKey]}
+	serving := secret.Data[corev1.TLSCertKey]
+
+	if !pki.ValidServingCert(pki.KeyPair{Cert: serving, Key: secret.Data[corev1.TLSPrivateKeyKey]}, ca, c.dnsNames(), nil, time.Now()) {
+		t.Fatal("expected a serving certificate signed by the CA")
+	}
+	if _, ok := secret.Data[previousCACertKey]; ok {
+		t.Error("expected no previous CA")
+	}
+	expectCABundle(t, c, ca.Cert)
+	expectServingCert(t, c, serving)
+
+	// Valid certificates are kept.
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if secret := certSecret(t, c); !bytes.Equal(secret.Data[caCertKey], ca.Cert) {
+		t.Error("expected the CA to be kept")
+	}
+	expectCABundle(t, c, ca.Cert)
+	expectServingCert(t, c, serving)
+}
+
+func TestCertRotatorEnsureCARenewal(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	oldCA := newExpiringCA(t, now.Add(pki.RenewBefore/2))
+	c := newTestCertRotator(t)
+
+	oldServing, err := pki.NewServingCert(oldCA, c.dnsNames()[0], c.dnsNames(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Client.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: CertSecretName, Namespace: testOperatorNamespace},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			caCertKey:               oldCA.Cert,
+			caKeyKey:                oldCA.Key,
+			corev1.TLSCertKey:       oldServing.Cert,
+			corev1.TLSPrivateKeyKey: oldServing.Key,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The renewed CA is trusted along with the previous one, before any serving certificate is issued by it.
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	secret := certSecret(t, c)
+	newCA := secret.Data[caCertKey]
+	if bytes.Equal(newCA, oldCA.Cert) {
+		t.Fatal("expected the CA to be renewed")
+	}
+	if !bytes.Equal(secret.Data[previousCACertKey], oldCA.Cert) {
+		t.Error("expected the previous CA to be kept")
+	}
+
+	bothCAs := append(append([]byte{}, newCA...), oldCA.Cert...)
+	expectCABundle(t, c, bothCAs)
+	expectServingCert(t, c, oldServing.Cert)
+
+	// Once the CA bundle is in place, the serving certificate is issued by the new CA.
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	secret = certSecret(t, c)
+	newServing := pki.KeyPair{Cert: secret.Data[corev1.TLSCertKey], Key: secret.Data[corev1.TLSPrivateKeyKey]}
+	if !pki.ValidServingCert(newServing, pki.KeyPair{Cert: newCA}, c.dnsNames(), nil, now) {
+		t.Fatal("expected a serving certificate signed by the new CA")
+	}
+	expectCABundle(t, c, bothCAs)
+	expectServingCert(t, c, newServing.Cert)
+
+	// The previous CA stays trusted until every replica had the time to load the new serving certificate.
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	expectCABundle(t, c, bothCAs)
+
+	c.now = func() time.Time { return now.Add(previousCAGracePeriod) }
+	if err := c.Ensure(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	secret = certSecret(t, c)
+	if _, ok := secret.Data[previousCACertKey]; ok {
+		t.Error("expected the previous CA to be dropped")
+	}
+	expectCABundle(t, c, newCA)
+	expectServingCert(t, c, newServing.Cert)
+}
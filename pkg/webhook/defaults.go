@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ptr "k8s.io/utils/pointer"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// StackDefaulter completes the spec of the Stacks with the values the operator deploys them with, so that the stored
+// object shows exactly what is running.
+type StackDefaulter struct{}
+
+var _ admission.CustomDefaulter = &StackDefaulter{}
+
+func (d *StackDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	stack, ok := obj.(*v1alpha2.Stack)
+	if !ok {
+		return fmt.Errorf("expected a Stack but got %T", obj)
+	}
+
+	var oldStack *v1alpha2.Stack
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation == admissionv1.Update && len(req.OldObject.Raw) > 0 {
+		oldStack = &v1alpha2.Stack{}
+		if err := json.Unmarshal(req.OldObject.Raw, oldStack); err != nil {
+			return fmt.Errorf("failed to decode the old stack: %w", err)
+		}
+	}
+
+	DefaultStack(stack, oldStack)
+
+	return nil
+}
+
+// DefaultStack sets the defaults of all the fields of the stack components. Optional components which are not deployed
+// are not added. The images of a version change which still match the release of the old version are moved to the release of
+// the new version, so that the defaulted images don't pin the stack to the version it was created with. Stacks of
+// unknown versions are left alone, they are rejected by the validation.
+func DefaultStack(stack, oldStack *v1alpha2.Stack) {
+	rel, err := release.Get(stack.Spec.Version)
+	if err != nil {
+		return
+	}
+
+	services := &stack.Spec.Services
+
+	if oldStack != nil && oldStack.Spec.Version != stack.Spec.Version {
+		if oldRelease, err := release.Get(oldStack.Spec.Version); err == nil {
+			unpinReleaseImages(services, oldRelease)
+		}
+	}
+
+	// tink-server and tink-controller are always deployed, they are added to the spec if they are not set.
+	if services.TinkServer == nil {
+		services.TinkServer = &v1alpha2.TinkServer{}
+	}
+	defaultImage(&services.TinkServer.Image, rel.TinkServer)
+	defaultComponentDeployment(&services.TinkServer.ComponentDeployment)
+	if services.TinkServer.Port == 0 {
+		services.TinkServer.Port = resources.TinkServerGRPCPort
+	}
+
+	if services.TinkController == nil {
+		services.TinkController = &v1alpha2.TinkController{}
+	}
+	defaultImage(&services.TinkController.Image, rel.TinkController)
+	defaultComponentDeployment(&services.TinkController.ComponentDeployment)
+
+	if hegel := services.Hegel; hegel != nil {
+		defaultImage(&hegel.Image, rel.Hegel)
+		defaultComponentDeployment(&hegel.ComponentDeployment)
+		if hegel.Port == 0 {
+			hegel.Port = resources.HegelHTTPPort
+		}
+	}
+
+	if rufio := services.Rufio; rufio != nil {
+		defaultImage(&rufio.Image, rel.Rufio)
+		defaultComponentDeployment(&rufio.ComponentDeployment)
+	}
+
+	if smee := services.Smee; smee != nil {
+		defaultSmee(smee, rel)
+	}
+
+	// The nginx proxy and the Hook download are always deployed as well.
+	if stack.Spec.Nginx == nil {
+		stack.Spec.Nginx = &v1alpha2.Nginx{}
+	}
+	defaultComponentDeployment(&stack.Spec.Nginx.ComponentDeployment)
+
+	if stack.Spec.HookArtifacts == nil {
+		stack.Spec.HookArtifacts = &v1alpha2.HookArtifacts{}
+	}
+	if stack.Spec.HookArtifacts.Download == nil {
+		stack.Spec.HookArtifacts.Download = &v1alpha2.ComponentDeployment{}
+	}
+	if stack.Spec.HookArtifacts.Download.Resources == nil {
+		defaults := resources.DefaultHookDownloadResources()
+		stack.Spec.HookArtifacts.Download.Resources = &defaults
+	}
+}
+
+func defaultSmee(smee *v1alpha2.Smee, rel release.Release) {
+	defaultImage(&smee.Image, rel.Smee)
+	defaultComponentDeployment(&smee.ComponentDeployment)
+
+	if smee.LogLevel == nil || *smee.LogLevel == "" {
+		smee.LogLevel = ptr.String(boots.DefaultLogLevel)
+	}
+
+	if smee.BackendConfigs.BackendKubeMode == nil && smee.BackendConfigs.BackendFileMode == nil {
+		smee.BackendConfigs.BackendKubeMode = &v1alpha2.BackendKubeMode{}
+	}
+
+	if smee.DHCPConfigs == nil {
+		smee.DHCPConfigs = &v1alpha2.DHCPConfigs{}
+	}
+	defaultAddress(&smee.DHCPConfigs.IP, &smee.DHCPConfigs.Port, boots.DefaultDHCPPort)
+
+	if smee.TFTPConfigs == nil {
+		smee.TFTPConfigs = &v1alpha2.TFTPConfigs{}
+	}
+	defaultAddress(&smee.TFTPConfigs.IP, &smee.TFTPConfigs.Port, boots.DefaultTFTPPort)
+
+	if smee.SyslogConfigs == nil {
+		smee.SyslogConfigs = &v1alpha2.SyslogConfigs{}
+	}
+	defaultAddress(&smee.SyslogConfigs.IP, &smee.SyslogConfigs.Port, boots.DefaultSyslogPort)
+
+	if smee.IPXEConfigs == nil {
+		smee.IPXEConfigs = &v1alpha2.IPXEConfigs{}
+	}
+	defaultAddress(&smee.IPXEConfigs.IP, &smee.IPXEConfigs.Port, boots.DefaultHTTPPort)
+}
+
+func defaultAddress(ip *string, port *int, defaultPort int) {
+	if *ip == "" {
+		*ip = boots.DefaultListenIP
+	}
+
+	if *port == 0 {
+		*port = defaultPort
+	}
+}
+
+func defaultImage(image *v1alpha2.Image, releaseImage v1alpha2.Image) {
+	if image.Repository == "" {
+		image.Repository = releaseImage.Repository
+	}
+
+	if image.Tag == "" {
+		image.Tag = releaseImage.Tag
+	}
+}
+
+func defaultComponentDeployment(deployment *v1alpha2.ComponentDeployment) {
+	if deployment.Resources == nil {
+		defaults := resources.DefaultResources()
+		deployment.Resources = &defaults
+	}
+
+	if deployment.Replicas == nil {
+		deployment.Replicas = ptr.Int32(1)
+	}
+}
+
+// unpinReleaseImages clears the image fields of the stack components which hold the default of the given release.
+func unpinReleaseImages(services *v1alpha2.Services, rel release.Release) {
+	if services.TinkServer != nil {
+		unpinImage(&services.TinkServer.Image, rel.TinkServer)
+	}
+
+	if services.TinkController != nil {
+		unpinImage(&services.TinkController.Image, rel.TinkController)
+	}
+
+	if services.Hegel != nil {
+		unpinImage(&services.Hegel.Image, rel.Hegel)
+	}
+
+	if services.Rufio != nil {
+		unpinImage(&services.Rufio.Image, rel.Rufio)
+	}
+
+	if services.Smee != nil {
+		unpinImage(&services.Smee.Image, rel.Smee)
+	}
+}
+
+func unpinImage(image *v1alpha2.Image, releaseImage v1alpha2.Image) {
+	if image.Repository == releaseImage.Repository {
+		image.Repository = ""
+	}
+
+	if image.Tag == releaseImage.Tag {
+		image.Tag = ""
+	}
+}
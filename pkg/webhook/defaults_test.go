@@ -0,0 +1,309 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/diff"
+	ptr "k8s.io/utils/pointer"
+)
+
+func defaultedDeployment(res corev1.ResourceRequirements, replicas *int32) v1alpha2.ComponentDeployment {
+	return v1alpha2.ComponentDeployment{Resources: &res, Replicas: replicas}
+}
+
+func TestDefaultStack(t *testing.T) {
+	rel, err := release.Get(testVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customResources := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+	}
+
+	tests := map[string]struct {
+		spec     v1alpha2.StackSpec
+		expected v1alpha2.StackSpec
+	}{
+		"minimal stack": {
+			spec: v1alpha2.StackSpec{Version: testVersion},
+			expected: v1alpha2.StackSpec{
+				Version: testVersion,
+				Services: v1alpha2.Services{
+					TinkServer: &v1alpha2.TinkServer{
+						Image:               rel.TinkServer,
+						Port:                resources.TinkServerGRPCPort,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					TinkController: &v1alpha2.TinkController{
+						Image:               rel.TinkController,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+				},
+				Nginx: &v1alpha2.Nginx{
+					ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+				},
+				HookArtifacts: &v1alpha2.HookArtifacts{
+					Download: &v1alpha2.ComponentDeployment{Resources: ptrResources(resources.DefaultHookDownloadResources())},
+				},
+			},
+		},
+		"all services": {
+			spec: v1alpha2.StackSpec{
+				Version: testVersion,
+				Services: v1alpha2.Services{
+					Smee:  &v1alpha2.Smee{},
+					Hegel: &v1alpha2.Hegel{},
+					Rufio: &v1alpha2.Rufio{},
+				},
+			},
+			expected: v1alpha2.StackSpec{
+				Version: testVersion,
+				Services: v1alpha2.Services{
+					TinkServer: &v1alpha2.TinkServer{
+						Image:               rel.TinkServer,
+						Port:                resources.TinkServerGRPCPort,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					TinkController: &v1alpha2.TinkController{
+						Image:               rel.TinkController,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					Hegel: &v1alpha2.Hegel{
+						Image:               rel.Hegel,
+						Port:                resources.HegelHTTPPort,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					Rufio: &v1alpha2.Rufio{
+						Image:               rel.Rufio,
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					Smee: &v1alpha2.Smee{
+						Image:    rel.Smee,
+						LogLevel: ptr.String(boots.DefaultLogLevel),
+						BackendConfigs: v1alpha2.BackendConfigs{
+							BackendKubeMode: &v1alpha2.BackendKubeMode{},
+						},
+						DHCPConfigs:         &v1alpha2.DHCPConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultDHCPPort},
+						TFTPConfigs:         &v1alpha2.TFTPConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultTFTPPort},
+						SyslogConfigs:       &v1alpha2.SyslogConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultSyslogPort},
+						IPXEConfigs:         &v1alpha2.IPXEConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultHTTPPort},
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+				},
+				Nginx: &v1alpha2.Nginx{
+					ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+				},
+				HookArtifacts: &v1alpha2.HookArtifacts{
+					Download: &v1alpha2.ComponentDeployment{Resources: ptrResources(resources.DefaultHookDownloadResources())},
+				},
+			},
+		},
+		"set values are kept": {
+			spec: v1alpha2.StackSpec{
+				Version: testVersion,
+				Services: v1alpha2.Services{
+					TinkServer: &v1alpha2.TinkServer{
+						Image:               v1alpha2.Image{Repository: "registry.local/tink"},
+						Port:                42114,
+						ComponentDeployment: defaultedDeployment(customResources, ptr.Int32(2)),
+					},
+					TinkController: &v1alpha2.TinkController{
+						Image: v1alpha2.Image{Tag: "v0.8.1"},
+					},
+					Smee: &v1alpha2.Smee{
+						LogLevel: ptr.String("info"),
+						BackendConfigs: v1alpha2.BackendConfigs{
+							BackendFileMode: &v1alpha2.BackendFileMode{FilePath: "/hardware.yaml"},
+						},
+						DHCPConfigs: &v1alpha2.DHCPConfigs{IP: "192.168.1.10", Port: 1067},
+					},
+				},
+				Nginx: &v1alpha2.Nginx{
+					ComponentDeployment: v1alpha2.ComponentDeployment{Replicas: ptr.Int32(2)},
+				},
+				HookArtifacts: &v1alpha2.HookArtifacts{
+					Version:  "v0.7.0",
+					Download: &v1alpha2.ComponentDeployment{Resources: &customResources},
+				},
+			},
+			expected: v1alpha2.StackSpec{
+				Version: testVersion,
+				Services: v1alpha2.Services{
+					TinkServer: &v1alpha2.TinkServer{
+						Image:               v1alpha2.Image{Repository: "registry.local/tink", Tag: rel.TinkServer.Tag},
+						Port:                42114,
+						ComponentDeployment: defaultedDeployment(customResources, ptr.Int32(2)),
+					},
+					TinkController: &v1alpha2.TinkController{
+						Image:               v1alpha2.Image{Repository: rel.TinkController.Repository, Tag: "v0.8.1"},
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+					Smee: &v1alpha2.Smee{
+						Image:    rel.Smee,
+						LogLevel: ptr.String("info"),
+						BackendConfigs: v1alpha2.BackendConfigs{
+							BackendFileMode: &v1alpha2.BackendFileMode{FilePath: "/hardware.yaml"},
+						},
+						DHCPConfigs:         &v1alpha2.DHCPConfigs{IP: "192.168.1.10", Port: 1067},
+						TFTPConfigs:         &v1alpha2.TFTPConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultTFTPPort},
+						SyslogConfigs:       &v1alpha2.SyslogConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultSyslogPort},
+						IPXEConfigs:         &v1alpha2.IPXEConfigs{IP: boots.DefaultListenIP, Port: boots.DefaultHTTPPort},
+						ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(1)),
+					},
+				},
+				Nginx: &v1alpha2.Nginx{
+					ComponentDeployment: defaultedDeployment(resources.DefaultResources(), ptr.Int32(2)),
+				},
+				HookArtifacts: &v1alpha2.HookArtifacts{
+					Version:  "v0.7.0",
+					Download: &v1alpha2.ComponentDeployment{Resources: &customResources},
+				},
+			},
+		},
+		"unknown version": {
+			spec:     v1alpha2.StackSpec{Version: "v0.0.1"},
+			expected: v1alpha2.StackSpec{Version: "v0.0.1"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			stack := &v1alpha2.Stack{Spec: *test.spec.DeepCopy()}
+			DefaultStack(stack, nil)
+
+			if !apiequality.Semantic.DeepEqual(stack.Spec, test.expected) {
+				t.Errorf("unexpected defaults:\n%s", diff.ObjectReflectDiff(test.expected, stack.Spec))
+			}
+
+			// The defaults are stable, defaulting the stored stack again doesn't change it.
+			defaulted := stack.DeepCopy()
+			DefaultStack(defaulted, stack)
+
+			if !apiequality.Semantic.DeepEqual(defaulted, stack) {
+				t.Errorf("defaulting is not idempotent:\n%s", diff.ObjectReflectDiff(stack, defaulted))
+			}
+		})
+	}
+}
+
+func TestDefaultStackVersionChange(t *testing.T) {
+	rel, err := release.Get(testVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	custom := v1alpha2.Image{Repository: "registry.local/tink", Tag: "custom"}
+
+	// The images of an unknown old version can't be told apart from custom images, they are kept.
+	oldStack := &v1alpha2.Stack{Spec: v1alpha2.StackSpec{Version: "v0.0.1"}}
+	stack := &v1alpha2.Stack{
+		Spec: v1alpha2.StackSpec{
+			Version: testVersion,
+			Services: v1alpha2.Services{
+				TinkServer: &v1alpha2.TinkServer{Image: custom},
+			},
+		},
+	}
+
+	DefaultStack(stack, oldStack)
+
+	if image := stack.Spec.Services.TinkServer.Image; image != custom {
+		t.Errorf("expected the image %v to be kept, got %v", custom, image)
+	}
+
+	if image := stack.Spec.Services.TinkController.Image; image != rel.TinkController {
+		t.Errorf("expected the release image %v, got %v", rel.TinkController, image)
+	}
+}
+
+func TestUnpinReleaseImages(t *testing.T) {
+	oldRelease := release.Release{
+		Version:        "v0.7.0",
+		Smee:           v1alpha2.Image{Repository: "quay.io/tinkerbell/boots", Tag: "v0.7.0"},
+		Hegel:          v1alpha2.Image{Repository: "quay.io/tinkerbell/hegel", Tag: "v0.7.0"},
+		Rufio:          v1alpha2.Image{Repository: "quay.io/tinkerbell/rufio", Tag: "v0.0.9"},
+		TinkServer:     v1alpha2.Image{Repository: "quay.io/tinkerbell/tink", Tag: "v0.7.0"},
+		TinkController: v1alpha2.Image{Repository: "quay.io/tinkerbell/tink-controller", Tag: "v0.7.0"},
+	}
+
+	rel, err := release.Get(testVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		services v1alpha2.Services
+		expected v1alpha2.Services
+	}{
+		"release images are unpinned": {
+			services: v1alpha2.Services{
+				TinkServer:     &v1alpha2.TinkServer{Image: oldRelease.TinkServer},
+				TinkController: &v1alpha2.TinkController{Image: oldRelease.TinkController},
+				Hegel:          &v1alpha2.Hegel{Image: oldRelease.Hegel},
+				Rufio:          &v1alpha2.Rufio{Image: oldRelease.Rufio},
+				Smee:           &v1alpha2.Smee{Image: oldRelease.Smee},
+			},
+			expected: v1alpha2.Services{
+				TinkServer:     &v1alpha2.TinkServer{},
+				TinkController: &v1alpha2.TinkController{},
+				Hegel:          &v1alpha2.Hegel{},
+				Rufio:          &v1alpha2.Rufio{},
+				Smee:           &v1alpha2.Smee{},
+			},
+		},
+		"custom images are kept": {
+			services: v1alpha2.Services{
+				TinkServer: &v1alpha2.TinkServer{Image: v1alpha2.Image{Repository: "registry.local/tink", Tag: "custom"}},
+			},
+			expected: v1alpha2.Services{
+				TinkServer: &v1alpha2.TinkServer{Image: v1alpha2.Image{Repository: "registry.local/tink", Tag: "custom"}},
+			},
+		},
+		"tags of the new release are kept": {
+			services: v1alpha2.Services{
+				Hegel: &v1alpha2.Hegel{Image: rel.Hegel},
+			},
+			expected: v1alpha2.Services{
+				Hegel: &v1alpha2.Hegel{Image: v1alpha2.Image{Tag: rel.Hegel.Tag}},
+			},
+		},
+		"only the matching part of an image is unpinned": {
+			services: v1alpha2.Services{
+				TinkServer:     &v1alpha2.TinkServer{Image: v1alpha2.Image{Repository: "registry.local/tink", Tag: oldRelease.TinkServer.Tag}},
+				TinkController: &v1alpha2.TinkController{Image: v1alpha2.Image{Repository: oldRelease.TinkController.Repository, Tag: "custom"}},
+			},
+			expected: v1alpha2.Services{
+				TinkServer:     &v1alpha2.TinkServer{Image: v1alpha2.Image{Repository: "registry.local/tink"}},
+				TinkController: &v1alpha2.TinkController{Image: v1alpha2.Image{Tag: "custom"}},
+			},
+		},
+		"unset services": {
+			services: v1alpha2.Services{},
+			expected: v1alpha2.Services{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			services := *test.services.DeepCopy()
+			unpinReleaseImages(&services, oldRelease)
+
+			if !apiequality.Semantic.DeepEqual(services, test.expected) {
+				t.Errorf("unexpected images:\n%s", diff.ObjectReflectDiff(test.expected, services))
+			}
+		})
+	}
+}
+
+func ptrResources(res corev1.ResourceRequirements) *corev1.ResourceRequirements {
+	return &res
+}
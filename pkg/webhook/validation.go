@@ -0,0 +1,635 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/hook"
+	"github.com/tinkerbell/operator/pkg/release"
+	"github.com/tinkerbell/operator/pkg/resources"
+	"github.com/tinkerbell/operator/pkg/resources/boots"
+	"github.com/tinkerbell/operator/pkg/resources/tink"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var (
+	// repositoryRegexp matches an image repository, e.g. quay.io/tinkerbell/smee or localhost:5000/smee, following the
+	// distribution reference grammar.
+	repositoryRegexp = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// tagRegexp matches an image tag.
+	tagRegexp = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	// digestRegexp matches an image digest.
+	digestRegexp = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-fA-F0-9]{32,}$`)
+	// registryRegexp matches a registry host with an optional port and path, e.g. registry.local:5000/mirror.
+	registryRegexp = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+	// sha512Regexp matches a hex encoded sha512 checksum.
+	sha512Regexp = regexp.MustCompile(`^[a-fA-F0-9]{128}$`)
+)
+
+// StackValidator rejects Stacks the operator can't deploy, e.g. with invalid addresses, colliding ports, unknown
+// versions or bad image references, and spec changes the deployed stack can't follow.
+type StackValidator struct{}
+
+var _ admission.CustomValidator = &StackValidator{}
+
+func (v *StackValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	stack, ok := obj.(*v1alpha2.Stack)
+	if !ok {
+		return nil, fmt.Errorf("expected a Stack but got %T", obj)
+	}
+
+	return nil, invalid(stack, ValidateStack(stack))
+}
+
+func (v *StackValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldStack, ok := oldObj.(*v1alpha2.Stack)
+	if !ok {
+		return nil, fmt.Errorf("expected a Stack but got %T", oldObj)
+	}
+
+	stack, ok := newObj.(*v1alpha2.Stack)
+	if !ok {
+		return nil, fmt.Errorf("expected a Stack but got %T", newObj)
+	}
+
+	// The stack is torn down, the spec doesn't matter anymore and the finalizer must be removable.
+	if !stack.DeletionTimestamp.IsZero() {
+		return nil, nil
+	}
+
+	errs := ValidateStack(stack)
+	errs = append(errs, ValidateStackUpdate(oldStack, stack)...)
+
+	return nil, invalid(stack, errs)
+}
+
+func (v *StackValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func invalid(stack *v1alpha2.Stack, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return kerrors.NewInvalid(v1alpha2.GroupVersion.WithKind("Stack").GroupKind(), stack.Name, errs)
+}
+
+// ValidateStack validates the spec of the given stack.
+func ValidateStack(stack *v1alpha2.Stack) field.ErrorList {
+	spec := &stack.Spec
+	specPath := field.NewPath("spec")
+
+	var errs field.ErrorList
+
+	rel, err := release.Get(spec.Version)
+	if err != nil {
+		errs = append(errs, field.NotSupported(specPath.Child("version"), spec.Version, release.Versions()))
+	}
+
+	if spec.Registry != nil && *spec.Registry != "" && !registryRegexp.MatchString(*spec.Registry) {
+		errs = append(errs, field.Invalid(specPath.Child("registry"), *spec.Registry, "must be a registry host with an optional port and path"))
+	}
+
+	errs = append(errs, validateIP(specPath.Child("dnsResolverIP"), spec.DNSResolverIP)...)
+	errs = append(errs, validatePublicAddress(specPath.Child("publicAddress"), spec.PublicAddress)...)
+	errs = append(errs, validateServices(specPath.Child("services"), &spec.Services)...)
+
+	if spec.Nginx != nil {
+		nginxPath := specPath.Child("nginx")
+		errs = append(errs, validateComponentDeployment(nginxPath, spec.Nginx.ComponentDeployment)...)
+
+		// The Hook artifacts are published into a single volume, every replica has to see it.
+		if replicas := spec.Nginx.Replicas; replicas != nil && *replicas > 1 && tink.HookStorageNodeLocal(tink.HookStorage(stack)) {
+			errs = append(errs, field.Invalid(nginxPath.Child("replicas"), *replicas,
+				"must be 1 unless the Hook artifacts storage is EmptyDir or a ReadWriteMany PersistentVolumeClaim"))
+		}
+	}
+
+	if spec.HookArtifacts != nil {
+		errs = append(errs, validateHookArtifacts(specPath.Child("hookArtifacts"), spec.HookArtifacts)...)
+
+		// The archives can only be resolved against a known release, e.g. a custom Hook version needs checksums.
+		if err == nil {
+			if _, _, err := hook.Archives(spec.HookArtifacts, rel.Hook); err != nil {
+				errs = append(errs, field.Invalid(specPath.Child("hookArtifacts"), spec.HookArtifacts.Version, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+// ValidateStackUpdate validates the transition of the stack spec from the old stack to the new one.
+func ValidateStackUpdate(oldStack, stack *v1alpha2.Stack) field.ErrorList {
+	specPath := field.NewPath("spec")
+
+	var errs field.ErrorList
+
+	if stack.Spec.Version != oldStack.Spec.Version {
+		errs = append(errs, validateVersionUpdate(specPath.Child("version"), oldStack.Spec.Version, stack.Spec.Version)...)
+	}
+
+	// The claim of the Hook artifacts is kept across the update, its class and access mode are immutable and the
+	// volume can't shrink.
+	oldStorage, storage := tink.HookStorage(oldStack), tink.HookStorage(stack)
+	if oldStorage.Type == v1alpha2.HookStorageTypePersistentVolumeClaim && storage.Type == v1alpha2.HookStorageTypePersistentVolumeClaim {
+		pvcPath := specPath.Child("hookArtifacts", "storage", "persistentVolumeClaim")
+		oldPVC, pvc := oldStorage.PersistentVolumeClaim, storage.PersistentVolumeClaim
+
+		if stringValue(oldPVC.StorageClassName) != stringValue(pvc.StorageClassName) {
+			errs = append(errs, field.Forbidden(pvcPath.Child("storageClassName"), "is immutable while the storage type is PersistentVolumeClaim"))
+		}
+
+		if oldPVC.AccessMode != pvc.AccessMode {
+			errs = append(errs, field.Forbidden(pvcPath.Child("accessMode"), "is immutable while the storage type is PersistentVolumeClaim"))
+		}
+
+		if pvc.Size.Cmp(*oldPVC.Size) < 0 {
+			errs = append(errs, field.Forbidden(pvcPath.Child("size"), fmt.Sprintf("can't be decreased from %s", oldPVC.Size.String())))
+		}
+	}
+
+	return errs
+}
+
+// validateVersionUpdate rejects version changes whose CRDs no longer serve the storage version of the CRDs of the old
+// version, the objects stored in it couldn't be read anymore.
+func validateVersionUpdate(fldPath *field.Path, oldVersion, version string) field.ErrorList {
+	oldRelease, err := release.Get(oldVersion)
+	if err != nil {
+		return nil
+	}
+
+	rel, err := release.Get(version)
+	if err != nil {
+		return nil
+	}
+
+	served := map[string]map[string]bool{}
+	for _, crd := range rel.CRDs {
+		served[crd.Name] = map[string]bool{}
+		for _, v := range crd.Spec.Versions {
+			served[crd.Name][v.Name] = v.Served
+		}
+	}
+
+	var dropped []string
+	for _, crd := range oldRelease.CRDs {
+		for _, v := range crd.Spec.Versions {
+			if v.Storage && !served[crd.Name][v.Name] {
+				dropped = append(dropped, fmt.Sprintf("%s %s", crd.Name, v.Name))
+			}
+		}
+	}
+
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	return field.ErrorList{field.Forbidden(fldPath, fmt.Sprintf("can't change from %s to %s, the CRDs of %s don't serve the stored versions %s",
+		oldVersion, version, version, strings.Join(dropped, ", ")))}
+}
+
+func validatePublicAddress(fldPath *field.Path, publicAddress *v1alpha2.PublicAddress) field.ErrorList {
+	if publicAddress == nil {
+		return nil
+	}
+
+	errs := validateIP(fldPath.Child("ip"), publicAddress.IP)
+
+	hasIP := publicAddress.IP != nil && *publicAddress.IP != ""
+	if !hasIP && publicAddress.Discovery == v1alpha2.PublicAddressDiscoveryLoadBalancer && stringValue(publicAddress.LoadBalancerService) == "" {
+		errs = append(errs, field.Required(fldPath.Child("loadBalancerService"), "is required by the LoadBalancer discovery"))
+	}
+
+	return errs
+}
+
+func validateServices(fldPath *field.Path, services *v1alpha2.Services) field.ErrorList {
+	var errs field.ErrorList
+
+	if tinkServer := services.TinkServer; tinkServer != nil {
+		tinkServerPath := fldPath.Child("tinkServer")
+		errs = append(errs, validateImage(tinkServerPath.Child("image"), tinkServer.Image)...)
+		errs = append(errs, validateComponentDeployment(tinkServerPath, tinkServer.ComponentDeployment)...)
+		errs = append(errs, validatePort(tinkServerPath.Child("port"), tinkServer.Port)...)
+
+		if cm := tinkServer.CertManager; cm != nil && cm.IssuerRef.Name == "" {
+			errs = append(errs, field.Required(tinkServerPath.Child("certManager", "issuerRef", "name"), ""))
+		}
+	}
+
+	if services.TinkController != nil {
+		tinkControllerPath := fldPath.Child("tinkController")
+		errs = append(errs, validateImage(tinkControllerPath.Child("image"), services.TinkController.Image)...)
+		errs = append(errs, validateComponentDeployment(tinkControllerPath, services.TinkController.ComponentDeployment)...)
+	}
+
+	if services.Hegel != nil {
+		hegelPath := fldPath.Child("hegel")
+		errs = append(errs, validateImage(hegelPath.Child("image"), services.Hegel.Image)...)
+		errs = append(errs, validateCIDRs(hegelPath.Child("trustedProxies"), services.Hegel.TrustedProxies)...)
+		errs = append(errs, validatePort(hegelPath.Child("port"), services.Hegel.Port)...)
+		errs = append(errs, validateComponentDeployment(hegelPath, services.Hegel.ComponentDeployment)...)
+	}
+
+	if services.Rufio != nil {
+		rufioPath := fldPath.Child("rufio")
+		errs = append(errs, validateImage(rufioPath.Child("image"), services.Rufio.Image)...)
+		errs = append(errs, validateComponentDeployment(rufioPath, services.Rufio.ComponentDeployment)...)
+	}
+
+	if services.Smee != nil {
+		errs = append(errs, validateSmee(fldPath.Child("smee"), services.Smee)...)
+	}
+
+	return append(errs, validatePorts(fldPath, services)...)
+}
+
+// unsupportedByBoots explains the rejection of the Smee settings boots v0.8.0, the Smee image of the release matrix,
+// has no flag or environment variable for.
+const unsupportedByBoots = "is not supported by boots v0.8.0, the Smee image of the supported Tinkerbell releases"
+
+func validateSmee(fldPath *field.Path, smee *v1alpha2.Smee) field.ErrorList {
+	errs := validateImage(fldPath.Child("image"), smee.Image)
+	errs = append(errs, validateComponentDeployment(fldPath, smee.ComponentDeployment)...)
+
+	backendPath := fldPath.Child("backendConfigs")
+	backend := smee.BackendConfigs
+	if backend.BackendKubeMode != nil && backend.BackendFileMode != nil {
+		errs = append(errs, field.Forbidden(backendPath, "backendKubeMode and backendFileMode are mutually exclusive"))
+	}
+
+	if backend.BackendFileMode != nil && backend.BackendFileMode.FilePath == "" {
+		errs = append(errs, field.Required(backendPath.Child("backendFileMode", "filePath"), ""))
+	}
+
+	if kube := backend.BackendKubeMode; kube != nil {
+		errs = append(errs, validateURL(backendPath.Child("backendKubeMode", "kubeAPIURL"), kube.KubeAPIURL)...)
+	}
+
+	if c := smee.DHCPConfigs; c != nil {
+		dhcpPath := fldPath.Child("dhcpConfigs")
+		errs = append(errs, validateIP(dhcpPath.Child("ip"), &c.IP)...)
+		errs = append(errs, validatePort(dhcpPath.Child("port"), c.Port)...)
+		errs = append(errs, validateIP(dhcpPath.Child("ipForPacket"), c.IPForPacket)...)
+		errs = append(errs, validateIP(dhcpPath.Child("syslogIP"), c.SyslogIP)...)
+		errs = append(errs, validateIPOrHostPort(dhcpPath.Child("tftpAddress"), c.TFTPAddress)...)
+		errs = append(errs, validateHostPort(dhcpPath.Child("httpIPXEBinaryAddress"), c.HTTPIPXEBinaryAddress)...)
+
+		if c.HTTPIPXEScriptURI != nil {
+			errs = append(errs, field.Forbidden(dhcpPath.Child("httpIPXEScriptURI"), unsupportedByBoots))
+		}
+	}
+
+	if c := smee.TFTPConfigs; c != nil {
+		tftpPath := fldPath.Child("tftpConfigs")
+		errs = append(errs, validateIP(tftpPath.Child("ip"), &c.IP)...)
+		errs = append(errs, validatePort(tftpPath.Child("port"), c.Port)...)
+
+		if c.TFTPTimeout != nil && *c.TFTPTimeout < 0 {
+			errs = append(errs, field.Invalid(tftpPath.Child("tftpTimeout"), *c.TFTPTimeout, "must not be negative"))
+		}
+
+		if c.IPXEScriptPatch != nil {
+			errs = append(errs, field.Forbidden(tftpPath.Child("ipxeScriptPatch"), unsupportedByBoots))
+		}
+	}
+
+	if c := smee.IPXEConfigs; c != nil {
+		ipxePath := fldPath.Child("ipxeConfigs")
+		errs = append(errs, validateIP(ipxePath.Child("ip"), &c.IP)...)
+		errs = append(errs, validatePort(ipxePath.Child("port"), c.Port)...)
+		errs = append(errs, validateHostPort(ipxePath.Child("tinkServerAddress"), c.TinkServerAddress)...)
+		errs = append(errs, validateURL(ipxePath.Child("hookURL"), c.HookURL)...)
+		errs = append(errs, validateCIDRs(ipxePath.Child("trustedProxies"), c.TrustedProxies)...)
+	}
+
+	if c := smee.SyslogConfigs; c != nil {
+		syslogPath := fldPath.Child("syslogConfigs")
+		errs = append(errs, validateIP(syslogPath.Child("ip"), &c.IP)...)
+		errs = append(errs, validatePort(syslogPath.Child("port"), c.Port)...)
+	}
+
+	return errs
+}
+
+// listenPort is a port the stack listens on, Smee on the host network and the nginx proxy in its pod.
+type listenPort struct {
+	port     int
+	protocol corev1.Protocol
+	owner    string
+	// path is the field the port is set through, it is nil for fixed and defaulted ports.
+	path *field.Path
+}
+
+// validatePorts rejects ports colliding with each other. The nginx proxy forwards the Smee ports next to its own, so
+// all the ports of the stack must be distinct per protocol.
+func validatePorts(fldPath *field.Path, services *v1alpha2.Services) field.ErrorList {
+	tinkServer := listenPort{port: resources.TinkServerGRPCPort, protocol: corev1.ProtocolTCP, owner: "tink-server"}
+	if services.TinkServer != nil && services.TinkServer.Port != 0 {
+		tinkServer.port, tinkServer.path = services.TinkServer.Port, fldPath.Child("tinkServer", "port")
+	}
+
+	ports := []listenPort{
+		tinkServer,
+		{port: resources.HookHTTPPort, protocol: corev1.ProtocolTCP, owner: "the Hook artifacts server"},
+	}
+
+	if hegel := services.Hegel; hegel != nil {
+		hegelPort := listenPort{port: resources.HegelHTTPPort, protocol: corev1.ProtocolTCP, owner: "hegel"}
+		if hegel.Port != 0 {
+			hegelPort.port, hegelPort.path = hegel.Port, fldPath.Child("hegel", "port")
+		}
+
+		ports = append(ports, hegelPort)
+	}
+
+	if smee := services.Smee; smee != nil {
+		smeePath := fldPath.Child("smee")
+		smeePorts := boots.ListenPorts(smee)
+
+		portPath := func(set bool, child string) *field.Path {
+			if !set {
+				return nil
+			}
+			return smeePath.Child(child, "port")
+		}
+
+		ports = append(ports,
+			listenPort{port: smeePorts.DHCP, protocol: corev1.ProtocolUDP, owner: "the smee DHCP server",
+				path: portPath(smee.DHCPConfigs != nil && smee.DHCPConfigs.Port != 0, "dhcpConfigs")},
+			listenPort{port: smeePorts.TFTP, protocol: corev1.ProtocolUDP, owner: "the smee TFTP server",
+				path: portPath(smee.TFTPConfigs != nil && smee.TFTPConfigs.Port != 0, "tftpConfigs")},
+			listenPort{port: smeePorts.Syslog, protocol: corev1.ProtocolUDP, owner: "the smee syslog server",
+				path: portPath(smee.SyslogConfigs != nil && smee.SyslogConfigs.Port != 0, "syslogConfigs")},
+			listenPort{port: smeePorts.HTTP, protocol: corev1.ProtocolTCP, owner: "the smee iPXE HTTP server",
+				path: portPath(smee.IPXEConfigs != nil && smee.IPXEConfigs.Port != 0, "ipxeConfigs")},
+		)
+	}
+
+	var errs field.ErrorList
+	for i, p := range ports {
+		for _, other := range ports[:i] {
+			if p.port != other.port || p.protocol != other.protocol {
+				continue
+			}
+
+			// The collision is reported on the port that is set, fixed and defaulted ports don't collide.
+			fldPath, owner := p.path, other.owner
+			if fldPath == nil {
+				fldPath, owner = other.path, p.owner
+			}
+			if fldPath == nil {
+				continue
+			}
+
+			errs = append(errs, field.Invalid(fldPath, p.port, fmt.Sprintf("%d/%s is already used by %s", p.port, p.protocol, owner)))
+		}
+	}
+
+	return errs
+}
+
+func validateHookArtifacts(fldPath *field.Path, hookArtifacts *v1alpha2.HookArtifacts) field.ErrorList {
+	var errs field.ErrorList
+
+	if hookArtifacts.URL != "" {
+		if u, err := url.Parse(hookArtifacts.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(fldPath.Child("url"), hookArtifacts.URL, "must be an http or https URL"))
+		}
+	}
+
+	if source := hookArtifacts.Source; source != nil {
+		sourcePath := fldPath.Child("source")
+		if _, err := hook.SourcePath(source); err != nil {
+			errs = append(errs, field.Invalid(sourcePath, "", err.Error()))
+		}
+
+		if source.Image != nil && source.Image.Image != "" && !validReference(source.Image.Image) {
+			errs = append(errs, field.Invalid(sourcePath.Child("image", "image"), source.Image.Image, "must be a valid image reference"))
+		}
+	}
+
+	for name, checksum := range hookArtifacts.Checksums {
+		if !sha512Regexp.MatchString(checksum) {
+			errs = append(errs, field.Invalid(fldPath.Child("checksums").Key(name), checksum, "must be a hex encoded sha512 checksum"))
+		}
+	}
+
+	if storage := hookArtifacts.Storage; storage != nil {
+		storagePath := fldPath.Child("storage")
+
+		if storage.PersistentVolumeClaim != nil {
+			if storage.Type != v1alpha2.HookStorageTypePersistentVolumeClaim {
+				errs = append(errs, field.Forbidden(storagePath.Child("persistentVolumeClaim"), "may only be set with the PersistentVolumeClaim storage type"))
+			} else if size := storage.PersistentVolumeClaim.Size; size != nil && size.Sign() <= 0 {
+				errs = append(errs, field.Invalid(storagePath.Child("persistentVolumeClaim", "size"), size.String(), "must be greater than zero"))
+			}
+		}
+
+		if storage.HostPath != nil {
+			hostPathType := storage.Type
+			if hostPathType == "" {
+				hostPathType = v1alpha2.HookStorageTypeHostPath
+			}
+
+			if hostPathType != v1alpha2.HookStorageTypeHostPath {
+				errs = append(errs, field.Forbidden(storagePath.Child("hostPath"), "may only be set with the HostPath storage type"))
+			} else if p := storage.HostPath.Path; p != "" && (!path.IsAbs(p) || path.Clean(p) == "/") {
+				errs = append(errs, field.Invalid(storagePath.Child("hostPath", "path"), p, "must be an absolute path other than /"))
+			}
+		}
+	}
+
+	if download := hookArtifacts.Download; download != nil {
+		downloadPath := fldPath.Child("download")
+		errs = append(errs, validateComponentDeployment(downloadPath, *download)...)
+
+		if download.Replicas != nil {
+			errs = append(errs, field.Forbidden(downloadPath.Child("replicas"), "is not supported by the Hook download"))
+		}
+	}
+
+	return errs
+}
+
+// validateComponentDeployment validates the deployment settings of a stack component which would otherwise only fail
+// once the deployment is applied. The affinity and topology spread constraints are left to the API server.
+func validateComponentDeployment(fldPath *field.Path, deployment v1alpha2.ComponentDeployment) field.ErrorList {
+	var errs field.ErrorList
+
+	if deployment.Replicas != nil && *deployment.Replicas < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("replicas"), *deployment.Replicas, "must be at least 1"))
+	}
+
+	errs = append(errs, metav1validation.ValidateLabels(deployment.NodeSelector, fldPath.Child("nodeSelector"))...)
+	errs = append(errs, metav1validation.ValidateLabels(deployment.Labels, fldPath.Child("labels"))...)
+	errs = append(errs, apivalidation.ValidateAnnotations(deployment.Annotations, fldPath.Child("annotations"))...)
+
+	if name := deployment.PriorityClassName; name != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(fldPath.Child("priorityClassName"), name, msg))
+		}
+	}
+
+	for i, toleration := range deployment.Tolerations {
+		errs = append(errs, validateToleration(fldPath.Child("tolerations").Index(i), toleration)...)
+	}
+
+	return errs
+}
+
+func validateToleration(fldPath *field.Path, toleration corev1.Toleration) field.ErrorList {
+	var errs field.ErrorList
+
+	if toleration.Key != "" {
+		for _, msg := range validation.IsQualifiedName(toleration.Key) {
+			errs = append(errs, field.Invalid(fldPath.Child("key"), toleration.Key, msg))
+		}
+	}
+
+	switch toleration.Operator {
+	case corev1.TolerationOpEqual, "":
+		if toleration.Key == "" {
+			errs = append(errs, field.Invalid(fldPath.Child("operator"), toleration.Operator, "must be Exists if the key is empty"))
+		}
+	case corev1.TolerationOpExists:
+		if toleration.Value != "" {
+			errs = append(errs, field.Invalid(fldPath.Child("value"), toleration.Value, "must be empty if the operator is Exists"))
+		}
+	default:
+		supported := []string{string(corev1.TolerationOpEqual), string(corev1.TolerationOpExists)}
+		errs = append(errs, field.NotSupported(fldPath.Child("operator"), toleration.Operator, supported))
+	}
+
+	switch toleration.Effect {
+	case "", corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+	default:
+		supported := []string{string(corev1.TaintEffectNoSchedule), string(corev1.TaintEffectPreferNoSchedule), string(corev1.TaintEffectNoExecute)}
+		errs = append(errs, field.NotSupported(fldPath.Child("effect"), toleration.Effect, supported))
+	}
+
+	return errs
+}
+
+func validateImage(fldPath *field.Path, image v1alpha2.Image) field.ErrorList {
+	var errs field.ErrorList
+	if image.Repository != "" && !repositoryRegexp.MatchString(image.Repository) {
+		errs = append(errs, field.Invalid(fldPath.Child("repository"), image.Repository, "must be a valid image repository"))
+	}
+
+	if image.Tag != "" && !tagRegexp.MatchString(image.Tag) {
+		errs = append(errs, field.Invalid(fldPath.Child("tag"), image.Tag, "must be a valid image tag"))
+	}
+
+	return errs
+}
+
+// validReference returns whether the given image reference is a repository with an optional tag and digest.
+func validReference(reference string) bool {
+	repository, digest, hasDigest := strings.Cut(reference, "@")
+	if hasDigest && !digestRegexp.MatchString(digest) {
+		return false
+	}
+
+	// A colon after the last slash separates the tag, a colon before it separates the registry port.
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		if !tagRegexp.MatchString(repository[i+1:]) {
+			return false
+		}
+		repository = repository[:i]
+	}
+
+	return repositoryRegexp.MatchString(repository)
+}
+
+func validateIP(fldPath *field.Path, ip *string) field.ErrorList {
+	if ip == nil || *ip == "" || net.ParseIP(*ip) != nil {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(fldPath, *ip, "must be a valid IP address")}
+}
+
+func validatePort(fldPath *field.Path, port int) field.ErrorList {
+	// Zero leaves the port at its default.
+	if port >= 0 && port <= 65535 {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(fldPath, port, "must be between 1 and 65535")}
+}
+
+func validateHostPort(fldPath *field.Path, address *string) field.ErrorList {
+	if address == nil || *address == "" {
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(*address)
+	if err != nil || host == "" {
+		return field.ErrorList{field.Invalid(fldPath, *address, "must be in the host:port format")}
+	}
+
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return field.ErrorList{field.Invalid(fldPath, *address, "must have a port between 1 and 65535")}
+	}
+
+	return nil
+}
+
+func validateIPOrHostPort(fldPath *field.Path, address *string) field.ErrorList {
+	if address == nil || net.ParseIP(*address) != nil {
+		return nil
+	}
+
+	return validateHostPort(fldPath, address)
+}
+
+func validateURL(fldPath *field.Path, rawURL *string) field.ErrorList {
+	if rawURL == nil || *rawURL == "" {
+		return nil
+	}
+
+	if u, err := url.Parse(*rawURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return field.ErrorList{field.Invalid(fldPath, *rawURL, "must be an absolute URL")}
+	}
+
+	return nil
+}
+
+func validateCIDRs(fldPath *field.Path, cidrs []string) field.ErrorList {
+	var errs field.ErrorList
+	for i, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), cidr, "must be a valid CIDR"))
+		}
+	}
+
+	return errs
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
@@ -0,0 +1,474 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+	"github.com/tinkerbell/operator/pkg/release"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ptr "k8s.io/utils/pointer"
+)
+
+const testVersion = "v0.8.0"
+
+// testStack returns a stack of the supported version with all the services enabled and the defaults applied.
+func testStack() *v1alpha2.Stack {
+	stack := &v1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "tinkerbell", Namespace: "tinkerbell"},
+		Spec: v1alpha2.StackSpec{
+			Version: testVersion,
+			Services: v1alpha2.Services{
+				Smee:  &v1alpha2.Smee{},
+				Hegel: &v1alpha2.Hegel{},
+				Rufio: &v1alpha2.Rufio{},
+			},
+		},
+	}
+
+	DefaultStack(stack, nil)
+
+	return stack
+}
+
+func pvcStorage(accessMode corev1.PersistentVolumeAccessMode, storageClass, size string) *v1alpha2.HookStorage {
+	quantity := resource.MustParse(size)
+
+	return &v1alpha2.HookStorage{
+		Type: v1alpha2.HookStorageTypePersistentVolumeClaim,
+		PersistentVolumeClaim: &v1alpha2.HookPersistentVolumeClaim{
+			StorageClassName: ptr.String(storageClass),
+			AccessMode:       accessMode,
+			Size:             &quantity,
+		},
+	}
+}
+
+func errorFields(errs field.ErrorList) []string {
+	fields := make([]string, 0, len(errs))
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	sort.Strings(fields)
+
+	return fields
+}
+
+func expectFields(t *testing.T, errs field.ErrorList, expected []string) {
+	t.Helper()
+
+	expected = append([]string(nil), expected...)
+	sort.Strings(expected)
+
+	if fields := errorFields(errs); strings.Join(fields, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected errors for %v, got %v", expected, errs)
+	}
+}
+
+func TestValidateStack(t *testing.T) {
+	tests := map[string]struct {
+		mutate func(*v1alpha2.Stack)
+		fields []string
+	}{
+		"defaulted stack": {
+			mutate: func(*v1alpha2.Stack) {},
+		},
+		"undefaulted stack": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec = v1alpha2.StackSpec{Version: testVersion}
+			},
+		},
+		"unknown version": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Version = "v0.0.1" },
+			fields: []string{"spec.version"},
+		},
+		"valid registry": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Registry = ptr.String("registry.local:5000/mirror") },
+		},
+		"invalid registry": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Registry = ptr.String("https://registry.local") },
+			fields: []string{"spec.registry"},
+		},
+		"valid image": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.TinkServer.Image = v1alpha2.Image{Repository: "localhost:5000/tinkerbell/tink", Tag: "v0.8.0-rc.1"}
+			},
+		},
+		"invalid image repository": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.TinkServer.Image.Repository = "Quay.io/Tinkerbell/Tink" },
+			fields: []string{"spec.services.tinkServer.image.repository"},
+		},
+		"invalid image tag": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Smee.Image.Tag = "-v0.8.0" },
+			fields: []string{"spec.services.smee.image.tag"},
+		},
+		"invalid DNS resolver IP": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.DNSResolverIP = ptr.String("10.96.0") },
+			fields: []string{"spec.dnsResolverIP"},
+		},
+		"invalid public IP": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.PublicAddress = &v1alpha2.PublicAddress{IP: ptr.String("tinkerbell.local")}
+			},
+			fields: []string{"spec.publicAddress.ip"},
+		},
+		"LoadBalancer discovery without service": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.PublicAddress = &v1alpha2.PublicAddress{Discovery: v1alpha2.PublicAddressDiscoveryLoadBalancer}
+			},
+			fields: []string{"spec.publicAddress.loadBalancerService"},
+		},
+		"invalid hegel trusted proxies": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Hegel.TrustedProxies = []string{"10.0.0.0/8", "10.0.0.1"} },
+			fields: []string{"spec.services.hegel.trustedProxies[1]"},
+		},
+		"tink-server cert manager without issuer": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.TinkServer.CertManager = &v1alpha2.CertManager{} },
+			fields: []string{"spec.services.tinkServer.certManager.issuerRef.name"},
+		},
+		"mutually exclusive smee backends": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.BackendConfigs.BackendFileMode = &v1alpha2.BackendFileMode{FilePath: "/hardware.yaml"}
+			},
+			fields: []string{"spec.services.smee.backendConfigs"},
+		},
+		"smee file backend without path": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.BackendConfigs = v1alpha2.BackendConfigs{BackendFileMode: &v1alpha2.BackendFileMode{}}
+			},
+			fields: []string{"spec.services.smee.backendConfigs.backendFileMode.filePath"},
+		},
+		"relative smee kube API URL": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.BackendConfigs.BackendKubeMode.KubeAPIURL = ptr.String("/api")
+			},
+			fields: []string{"spec.services.smee.backendConfigs.backendKubeMode.kubeAPIURL"},
+		},
+		"valid smee addresses": {
+			mutate: func(s *v1alpha2.Stack) {
+				dhcp := s.Spec.Services.Smee.DHCPConfigs
+				dhcp.IPForPacket = ptr.String("192.168.1.10")
+				dhcp.SyslogIP = ptr.String("192.168.1.10")
+				dhcp.TFTPAddress = ptr.String("192.168.1.10")
+				dhcp.HTTPIPXEBinaryAddress = ptr.String("192.168.1.10:80")
+
+				ipxe := s.Spec.Services.Smee.IPXEConfigs
+				ipxe.TinkServerAddress = ptr.String("tink.local:42113")
+				ipxe.HookURL = ptr.String("http://192.168.1.10:8080")
+				ipxe.TrustedProxies = []string{"10.244.0.0/16"}
+			},
+		},
+		"smee TFTP address with port": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.DHCPConfigs.TFTPAddress = ptr.String("192.168.1.10:69")
+			},
+		},
+		"invalid smee DHCP addresses": {
+			mutate: func(s *v1alpha2.Stack) {
+				dhcp := s.Spec.Services.Smee.DHCPConfigs
+				dhcp.IP = "0.0.0"
+				dhcp.IPForPacket = ptr.String("192.168.1")
+				dhcp.SyslogIP = ptr.String("syslog.local")
+				dhcp.TFTPAddress = ptr.String("192.168.1.10:tftp")
+				dhcp.HTTPIPXEBinaryAddress = ptr.String("192.168.1.10")
+			},
+			fields: []string{
+				"spec.services.smee.dhcpConfigs.ip",
+				"spec.services.smee.dhcpConfigs.ipForPacket",
+				"spec.services.smee.dhcpConfigs.syslogIP",
+				"spec.services.smee.dhcpConfigs.tftpAddress",
+				"spec.services.smee.dhcpConfigs.httpIPXEBinaryAddress",
+			},
+		},
+		"invalid smee listen addresses": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.SyslogConfigs.IP = "localhost"
+				s.Spec.Services.Smee.TFTPConfigs.IP = "localhost"
+				s.Spec.Services.Smee.IPXEConfigs.IP = "localhost"
+			},
+			fields: []string{
+				"spec.services.smee.syslogConfigs.ip",
+				"spec.services.smee.tftpConfigs.ip",
+				"spec.services.smee.ipxeConfigs.ip",
+			},
+		},
+		"invalid smee iPXE settings": {
+			mutate: func(s *v1alpha2.Stack) {
+				ipxe := s.Spec.Services.Smee.IPXEConfigs
+				ipxe.TinkServerAddress = ptr.String("tink.local:0")
+				ipxe.HookURL = ptr.String("192.168.1.10:8080")
+				ipxe.TrustedProxies = []string{"10.244.0.0"}
+			},
+			fields: []string{
+				"spec.services.smee.ipxeConfigs.tinkServerAddress",
+				"spec.services.smee.ipxeConfigs.hookURL",
+				"spec.services.smee.ipxeConfigs.trustedProxies[0]",
+			},
+		},
+		"negative smee TFTP timeout": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Smee.TFTPConfigs.TFTPTimeout = ptr.Int(-1) },
+			fields: []string{"spec.services.smee.tftpConfigs.tftpTimeout"},
+		},
+		"smee settings boots doesn't support": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.TFTPConfigs.IPXEScriptPatch = ptr.String("echo hello")
+				s.Spec.Services.Smee.DHCPConfigs.HTTPIPXEScriptURI = ptr.String("http://192.168.1.10/auto.ipxe")
+			},
+			fields: []string{
+				"spec.services.smee.tftpConfigs.ipxeScriptPatch",
+				"spec.services.smee.dhcpConfigs.httpIPXEScriptURI",
+			},
+		},
+		"port out of range": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Smee.SyslogConfigs.Port = 65536 },
+			fields: []string{"spec.services.smee.syslogConfigs.port"},
+		},
+		"colliding ports": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Hegel.Port = s.Spec.Services.TinkServer.Port },
+			fields: []string{"spec.services.hegel.port"},
+		},
+		"port colliding with the Hook artifacts server": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Smee.IPXEConfigs.Port = 8080 },
+			fields: []string{"spec.services.smee.ipxeConfigs.port"},
+		},
+		"same port of different protocols": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Services.Smee.SyslogConfigs.Port = s.Spec.Services.Hegel.Port },
+		},
+		"invalid component deployment": {
+			mutate: func(s *v1alpha2.Stack) {
+				tinkServer := s.Spec.Services.TinkServer
+				tinkServer.Replicas = ptr.Int32(0)
+				tinkServer.NodeSelector = map[string]string{"tinkerbell.org/provisioning!": "true"}
+				tinkServer.Labels = map[string]string{"app": "tink server"}
+				tinkServer.Annotations = map[string]string{"-invalid": ""}
+				tinkServer.PriorityClassName = "System-Cluster-Critical"
+			},
+			fields: []string{
+				"spec.services.tinkServer.replicas",
+				"spec.services.tinkServer.nodeSelector",
+				"spec.services.tinkServer.labels",
+				"spec.services.tinkServer.annotations",
+				"spec.services.tinkServer.priorityClassName",
+			},
+		},
+		"valid tolerations": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.Tolerations = []corev1.Toleration{
+					{Key: "tinkerbell.org/provisioning", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+					{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "tinkerbell"},
+					{Operator: corev1.TolerationOpExists},
+				}
+			},
+		},
+		"invalid tolerations": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Services.Smee.Tolerations = []corev1.Toleration{
+					{Key: "tinkerbell.org/provisioning!", Operator: corev1.TolerationOpExists},
+					{Operator: corev1.TolerationOpEqual},
+					{Key: "dedicated", Operator: corev1.TolerationOpExists, Value: "tinkerbell"},
+					{Key: "dedicated", Operator: "In"},
+					{Key: "dedicated", Effect: "NoRun"},
+				}
+			},
+			fields: []string{
+				"spec.services.smee.tolerations[0].key",
+				"spec.services.smee.tolerations[1].operator",
+				"spec.services.smee.tolerations[2].value",
+				"spec.services.smee.tolerations[3].operator",
+				"spec.services.smee.tolerations[4].effect",
+			},
+		},
+		"several nginx replicas with host path storage": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.Nginx.Replicas = ptr.Int32(2) },
+			fields: []string{"spec.nginx.replicas"},
+		},
+		"several nginx replicas with ReadWriteOnce storage": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Nginx.Replicas = ptr.Int32(2)
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "2Gi")
+			},
+			fields: []string{"spec.nginx.replicas"},
+		},
+		"several nginx replicas with ReadWriteMany storage": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Nginx.Replicas = ptr.Int32(2)
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteMany, "nfs", "2Gi")
+			},
+		},
+		"several nginx replicas with EmptyDir storage": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.Nginx.Replicas = ptr.Int32(2)
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{Type: v1alpha2.HookStorageTypeEmptyDir}
+			},
+		},
+		"Hook download replicas": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.HookArtifacts.Download.Replicas = ptr.Int32(1) },
+			fields: []string{"spec.hookArtifacts.download.replicas"},
+		},
+		"invalid Hook artifacts": {
+			mutate: func(s *v1alpha2.Stack) {
+				rel, _ := release.Get(testVersion)
+				checksums := map[string]string{}
+				for name, checksum := range rel.Hook.Checksums {
+					checksums[name] = checksum
+				}
+				checksums["vmlinuz-x86_64"] = "abc"
+
+				s.Spec.HookArtifacts.URL = "ftp://artifacts.local/hook"
+				s.Spec.HookArtifacts.Checksums = checksums
+			},
+			fields: []string{
+				"spec.hookArtifacts.url",
+				"spec.hookArtifacts.checksums[vmlinuz-x86_64]",
+			},
+		},
+		"custom Hook version without checksums": {
+			mutate: func(s *v1alpha2.Stack) { s.Spec.HookArtifacts.Version = "v0.9.0" },
+			fields: []string{"spec.hookArtifacts"},
+		},
+		"invalid Hook source image": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Source = &v1alpha2.HookArtifactsSource{Image: &v1alpha2.HookImageSource{Image: "Registry.local/Hook"}}
+			},
+			fields: []string{"spec.hookArtifacts.source.image.image"},
+		},
+		"relative Hook host path": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{HostPath: &v1alpha2.HookHostPath{Path: "opt/hook"}}
+			},
+			fields: []string{"spec.hookArtifacts.storage.hostPath.path"},
+		},
+		"host path of another Hook storage type": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{
+					Type:     v1alpha2.HookStorageTypeEmptyDir,
+					HostPath: &v1alpha2.HookHostPath{Path: "/opt/hook"},
+				}
+			},
+			fields: []string{"spec.hookArtifacts.storage.hostPath"},
+		},
+		"empty Hook PersistentVolumeClaim": {
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "0")
+			},
+			fields: []string{"spec.hookArtifacts.storage.persistentVolumeClaim.size"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			stack := testStack()
+			test.mutate(stack)
+
+			expectFields(t, ValidateStack(stack), test.fields)
+		})
+	}
+}
+
+func TestValidateStackUpdate(t *testing.T) {
+	tests := map[string]struct {
+		mutateOld func(*v1alpha2.Stack)
+		mutate    func(*v1alpha2.Stack)
+		fields    []string
+	}{
+		"unchanged stack": {
+			mutateOld: func(*v1alpha2.Stack) {},
+			mutate:    func(*v1alpha2.Stack) {},
+		},
+		"version change from an unknown version": {
+			mutateOld: func(s *v1alpha2.Stack) { s.Spec.Version = "v0.0.1" },
+			mutate:    func(*v1alpha2.Stack) {},
+		},
+		"grown Hook PersistentVolumeClaim": {
+			mutateOld: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "2Gi")
+			},
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "4Gi")
+			},
+		},
+		"changed Hook PersistentVolumeClaim": {
+			mutateOld: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "2Gi")
+			},
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteMany, "nfs", "1Gi")
+			},
+			fields: []string{
+				"spec.hookArtifacts.storage.persistentVolumeClaim.storageClassName",
+				"spec.hookArtifacts.storage.persistentVolumeClaim.accessMode",
+				"spec.hookArtifacts.storage.persistentVolumeClaim.size",
+			},
+		},
+		"defaulted Hook PersistentVolumeClaim": {
+			mutateOld: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{Type: v1alpha2.HookStorageTypePersistentVolumeClaim}
+			},
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{
+					Type:                  v1alpha2.HookStorageTypePersistentVolumeClaim,
+					PersistentVolumeClaim: &v1alpha2.HookPersistentVolumeClaim{AccessMode: corev1.ReadWriteOnce},
+				}
+			},
+		},
+		"changed Hook storage type": {
+			mutateOld: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = pvcStorage(corev1.ReadWriteOnce, "standard", "2Gi")
+			},
+			mutate: func(s *v1alpha2.Stack) {
+				s.Spec.HookArtifacts.Storage = &v1alpha2.HookStorage{Type: v1alpha2.HookStorageTypeEmptyDir}
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			oldStack, stack := testStack(), testStack()
+			test.mutateOld(oldStack)
+			test.mutate(stack)
+
+			expectFields(t, ValidateStackUpdate(oldStack, stack), test.fields)
+		})
+	}
+}
+
+func TestStackValidatorValidateUpdate(t *testing.T) {
+	invalid := testStack()
+	invalid.Spec.Version = "v0.0.1"
+
+	deleted := invalid.DeepCopy()
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+
+	tests := map[string]struct {
+		stack *v1alpha2.Stack
+		valid bool
+	}{
+		"valid stack": {
+			stack: testStack(),
+			valid: true,
+		},
+		"invalid stack": {
+			stack: invalid,
+		},
+		"deleted invalid stack": {
+			stack: deleted,
+			valid: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&StackValidator{}).ValidateUpdate(context.Background(), testStack(), test.stack)
+			if valid := err == nil; valid != test.valid {
+				t.Errorf("expected the update to be valid: %t, got error %v", test.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+// Package webhook serves the admission and conversion webhooks of the Stack API and manages their serving certificate.
+package webhook
+
+import (
+	"fmt"
+
+	"github.com/tinkerbell/operator/api/v1alpha2"
+
+	ctrlruntime "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Add registers the Stack webhooks with the webhook server of the manager. The conversion webhook is registered along
+// with them, as v1alpha2 is the hub of the Stack versions.
+func Add(mgr manager.Manager) error {
+	if err := ctrlruntime.NewWebhookManagedBy(mgr).
+		For(&v1alpha2.Stack{}).
+		WithDefaulter(&StackDefaulter{}).
+		WithValidator(&StackValidator{}).
+		Complete(); err != nil {
+		return fmt.Errorf("failed to create stack webhooks: %w", err)
+	}
+
+	return nil
+}